@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// requireOwner returns the caller's MSPID, or an error when the caller does not
+// belong to the MSP that currently owns the asset.
+func requireOwner(ctx contractapi.TransactionContextInterface, asset *Asset) (string, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	if clientMSPID != asset.Owner {
+		return "", fmt.Errorf("%w: client from %s is not the owner of asset %s, owned by %s", ErrUnauthorized, clientMSPID, asset.AssetID, asset.Owner)
+	}
+	return clientMSPID, nil
+}
+
+// requireOwnerOrAdmin is requireOwner, also letting the admin MSP through.
+func requireOwnerOrAdmin(ctx contractapi.TransactionContextInterface, asset *Asset) (string, error) {
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !admin {
+		return requireOwner(ctx, asset)
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	return clientMSPID, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// agentIndex is the composite key object type listing events by the agent
+// that recorded them and their time. The asset ID keeps the events a single
+// transaction recorded for several assets apart.
+const agentIndex = "agent~timestamp~txID~assetID"
+
+// indexAgent records the event, already stamped with its txID and timestamp,
+// under the agent that recorded it.
+func indexAgent(ctx contractapi.TransactionContextInterface, assetID string, event ProvenanceEvent) error {
+	if event.AgentID == "" {
+		return nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(agentIndex, []string{event.AgentID, event.Timestamp, event.TxID, assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create agent index key: %w", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put agent index: %w", err)
+	}
+	return nil
+}
+
+// AgentEvent is an event together with the asset it was recorded for.
+type AgentEvent struct {
+	AssetID string          `json:"assetID"`
+	Event   ProvenanceEvent `json:"event"`
+}
+
+// GetEventsByAgent returns every event agentID recorded between startTime and
+// endTime inclusive, across all assets and ordered by time, each with the ID of
+// its asset. Both bounds are optional RFC3339 timestamps. It reads the agent
+// index rather than every asset's events. Events of deleted assets are
+// included, as their records are kept; archived events and events recorded
+// before the index was introduced are not.
+func (s *SmartContract) GetEventsByAgent(ctx contractapi.TransactionContextInterface, agentID string, startTime string, endTime string) ([]AgentEvent, error) {
+	if agentID == "" {
+		return nil, fmt.Errorf("%w: agentID must not be empty", ErrInvalidArgument)
+	}
+	start, err := parseTimeBound("startTime", startTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeBound("endTime", endTime)
+	if err != nil {
+		return nil, err
+	}
+	if start != nil && end != nil && start.After(*end) {
+		return nil, fmt.Errorf("%w: startTime %s is after endTime %s", ErrInvalidArgument, startTime, endTime)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(agentIndex, []string{agentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent index: %w", err)
+	}
+	defer iterator.Close()
+
+	events := []AgentEvent{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate agent index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split agent index key: %w", err)
+		}
+		recorded, err := time.Parse(time.RFC3339, keyParts[1])
+		if err != nil {
+			continue
+		}
+		if (start != nil && recorded.Before(*start)) || (end != nil && recorded.After(*end)) {
+			continue
+		}
+		txID, assetID := keyParts[2], keyParts[3]
+		eventJSON, err := readEventJSON(ctx, txID, assetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %w", err)
+		}
+		if eventJSON == nil {
+			// The event was archived.
+			continue
+		}
+		var event ProvenanceEvent
+		err = json.Unmarshal(eventJSON, &event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event %s: %w", txID, err)
+		}
+		event.TxID = txID
+		events = append(events, AgentEvent{AssetID: assetID, Event: event})
+	}
+	return events, nil
+}
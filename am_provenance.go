@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
@@ -15,73 +17,264 @@ type SmartContract struct {
 
 // Asset represents the core item being tracked on the blockchain.
 type Asset struct {
+	SchemaVersion       int      `json:"schemaVersion"`
 	AssetID             string   `json:"assetID"`
 	Owner               string   `json:"owner"`
-	CurrentLifecycleStage string `json:"currentLifecycleStage"`
+	Custodian           string   `json:"custodian,omitempty" metadata:",optional"`
+	PendingTransfer     *PendingTransfer `json:"pendingTransfer,omitempty" metadata:",optional"`
+	CurrentLifecycleStage LifecycleStage `json:"currentLifecycleStage"`
 	HistoryTxIDs        []string `json:"historyTxIDs"`
+	InstalledIn         string   `json:"installedIn"`
+	CertificateID       string   `json:"certificateID"`
+	CertificateRevoked  bool     `json:"certificateRevoked"`
+	Failed              bool     `json:"failed"`
+	Held                bool     `json:"held"`
+	HoldReason          string   `json:"holdReason"`
+	Recalled            bool     `json:"recalled"`
+	Quantity            float64  `json:"quantity,omitempty" metadata:",optional"`
+	RemainingQuantity   float64  `json:"remainingQuantity,omitempty" metadata:",optional"`
+	ScrappedQuantity    float64  `json:"scrappedQuantity,omitempty" metadata:",optional"`
+	ParentAssetIDs      []string `json:"parentAssetIDs,omitempty" metadata:",optional"`
+	ChildAssetIDs       []string `json:"childAssetIDs,omitempty" metadata:",optional"`
+	Tags                map[string]string `json:"tags,omitempty" metadata:",optional"`
+	Metadata            map[string]string `json:"metadata,omitempty" metadata:",optional"`
+	LastEventTimestamp  string   `json:"lastEventTimestamp,omitempty" metadata:",optional"`
+	OpenNCRs            []string `json:"openNCRs,omitempty" metadata:",optional"`
+	ExpiryTimestamp     string   `json:"expiryTimestamp,omitempty" metadata:",optional"`
+	CertificationProposal *CertificationProposal `json:"certificationProposal,omitempty" metadata:",optional"`
 }
 
 // ProvenanceEvent is a comprehensive structure for ALL possible on-chain event data.
+// It is encoded to JSON as the typed event of its event type, so the ledger and
+// clients only see the fields that type carries; see typedEvent.
 type ProvenanceEvent struct {
-	EventType               string `json:"eventType"`
+	SchemaVersion           int    `json:"schemaVersion"`
+	EventType               EventType `json:"eventType"`
+	TxID                    string `json:"txID"`
 	AgentID                 string `json:"agentID"`
 	Timestamp               string `json:"timestamp"`
-	OffChainDataHash        string `json:"offChainDataHash"`
-	OnChainDataPayload      string `json:"onChainDataPayload"`
-	MaterialType            string `json:"materialType"`
-	MaterialBatchID         string `json:"materialBatchID"`
-	SupplierID              string `json:"supplierID"`
-	PrintJobID              string `json:"printJobID"`
-	MachineID               string `json:"machineID"`
-	MaterialUsedID          string `json:"materialUsedID"`
-	PrimaryInspectionResult string `json:"primaryInspectionResult"`
-	TestStandardApplied     string `json:"testStandardApplied"`
-	FinalTestResult         string `json:"finalTestResult"`
-	CertificateID           string `json:"certificateID"`
+	OffChainDataHash        string `json:"offChainDataHash" metadata:",optional"`
+	HashAlgorithm           string `json:"hashAlgorithm,omitempty" metadata:",optional"`
+	OnChainDataPayload      string `json:"onChainDataPayload" metadata:",optional"`
+	MaterialType            string `json:"materialType" metadata:",optional"`
+	MaterialBatchID         string `json:"materialBatchID" metadata:",optional"`
+	SupplierID              string `json:"supplierID" metadata:",optional"`
+	PrintJobID              string `json:"printJobID" metadata:",optional"`
+	MachineID               string `json:"machineID" metadata:",optional"`
+	MaterialUsedID          string `json:"materialUsedID" metadata:",optional"`
+	PrimaryInspectionResult string `json:"primaryInspectionResult" metadata:",optional"`
+	TestStandardApplied     string `json:"testStandardApplied" metadata:",optional"`
+	FinalTestResult         string `json:"finalTestResult" metadata:",optional"`
+	CertificateID           string `json:"certificateID" metadata:",optional"`
+	ParentSerialNumber      string `json:"parentSerialNumber" metadata:",optional"`
+	InstallationPosition    string `json:"installationPosition" metadata:",optional"`
+	FailureMode             string `json:"failureMode" metadata:",optional"`
+	ServiceHours            int    `json:"serviceHours" metadata:",optional"`
+	ClientEventID           string `json:"clientEventID,omitempty" metadata:",optional"`
+	Signature               string `json:"signature,omitempty" metadata:",optional"`
+	PreviousEventHash       string `json:"previousEventHash,omitempty" metadata:",optional"`
+	ExpiryTimestamp         string `json:"expiryTimestamp,omitempty" metadata:",optional"`
 }
 
 // HistoryResult is a wrapper object for returning an array of events.
+// Amendments maps the txID of each amended event to the txIDs of the
+// AMENDMENT events correcting it, oldest first.
 type HistoryResult struct {
-	Events []ProvenanceEvent `json:"events"`
+	Events     []ProvenanceEvent   `json:"events"`
+	Skipped    []string            `json:"skipped"`
+	Amendments map[string][]string `json:"amendments,omitempty" metadata:",optional"`
+}
+
+// eventObjectType is the composite key object type of event records.
+const eventObjectType = "event"
+
+// eventKey returns the world state key of the event a transaction recorded for
+// an asset, a composite key namespaced by the asset ID. A transaction touching
+// several assets keeps a distinct event for each of them, and an event can only
+// be found through the asset it was recorded for, even when its txID was
+// imported from another channel.
+func eventKey(ctx contractapi.TransactionContextInterface, txID string, assetID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(eventObjectType, []string{assetID, txID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create event key: %w", err)
+	}
+	return key, nil
+}
+
+// legacyEventKeys returns the keys an event was stored under before
+// MigrateEventKeys rekeyed it: EVENT_<txID>_<assetID>, and EVENT_<txID> before
+// events carried the asset ID.
+func legacyEventKeys(txID string, assetID string) []string {
+	return []string{"EVENT_" + txID + "_" + assetID, "EVENT_" + txID}
 }
 
 // recordEvent is an internal helper function.
-func (s *SmartContract) recordEvent(ctx contractapi.TransactionContextInterface, event ProvenanceEvent) (string, error) {
+func (s *SmartContract) recordEvent(ctx contractapi.TransactionContextInterface, assetID string, event ProvenanceEvent) (string, error) {
+	err := checkPayloadSize(ctx, event)
+	if err != nil {
+		return "", err
+	}
 	txID := ctx.GetStub().GetTxID()
-	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	timestamp, err := txTimestamp(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+		return "", err
 	}
-	event.Timestamp = txTimestamp.AsTime().UTC().Format(time.RFC3339)
+	event.SchemaVersion = currentSchemaVersion
+	event.TxID = txID
+	event.Timestamp = timestamp
 	eventJSON, err := json.Marshal(event)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal event JSON: %v", err)
+		return "", fmt.Errorf("failed to marshal event JSON: %w", err)
 	}
-	err = ctx.GetStub().PutState("EVENT_"+txID, eventJSON)
+	key, err := eventKey(ctx, txID, assetID)
 	if err != nil {
-		return "", fmt.Errorf("failed to put event state: %v", err)
+		return "", err
+	}
+	err = ctx.GetStub().PutState(key, eventJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to put event state: %w", err)
+	}
+	err = sequenceEvent(ctx, assetID, eventJSON)
+	if err != nil {
+		return "", err
+	}
+	err = indexAgent(ctx, assetID, event)
+	if err != nil {
+		return "", err
 	}
 	return txID, nil
 }
 
-// CreateMaterialCertification creates the initial asset.
-func (s *SmartContract) CreateMaterialCertification(ctx contractapi.TransactionContextInterface, assetID string, materialType string, materialBatchID string, supplierID string, offChainDataHash string) error {
+// recordAssetEvent records the event against the asset through appendAssetEvent
+// and emits the chaincode event of the transaction.
+func (s *SmartContract) recordAssetEvent(ctx contractapi.TransactionContextInterface, asset *Asset, event ProvenanceEvent, nextStage LifecycleStage) error {
+	err := s.appendAssetEvent(ctx, asset, event, nextStage)
+	if err != nil {
+		return err
+	}
+	return emitChaincodeEvent(ctx, event.EventType, asset.AssetID)
+}
+
+// appendAssetEvent records the event, appends its txID to the asset's history,
+// moves the asset to nextStage (left unchanged when empty) and writes it back.
+// It emits no chaincode event, so transactions touching several assets can
+// emit a single one for all of them.
+func (s *SmartContract) appendAssetEvent(ctx contractapi.TransactionContextInterface, asset *Asset, event ProvenanceEvent, nextStage LifecycleStage) error {
+	err := runValidators(ctx, &PendingEvent{Asset: asset, Event: &event, NextStage: nextStage})
+	if err != nil {
+		return err
+	}
+	if err := indexCreation(ctx, asset); err != nil {
+		return err
+	}
+	if event.OffChainDataHash != "" {
+		event.HashAlgorithm, event.OffChainDataHash, err = resolveOffChainHash(event.HashAlgorithm, event.OffChainDataHash)
+		if err != nil {
+			return err
+		}
+	}
+	event.PreviousEventHash, err = previousEventHash(ctx, asset)
+	if err != nil {
+		return err
+	}
+	txID, err := s.recordEvent(ctx, asset.AssetID, event)
+	if err != nil {
+		return err
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	event.TxID = txID
+	event.Timestamp = timestamp
+	if err := s.indexEvent(ctx, asset, event); err != nil {
+		return err
+	}
+	if nextStage != "" && nextStage != asset.CurrentLifecycleStage {
+		err = updateStageIndex(ctx, asset.AssetID, asset.CurrentLifecycleStage, nextStage)
+		if err != nil {
+			return err
+		}
+		asset.CurrentLifecycleStage = nextStage
+	}
+	asset.HistoryTxIDs = append(asset.HistoryTxIDs, txID)
+	asset.LastEventTimestamp = timestamp
+	return s.putAsset(ctx, asset)
+}
+
+// indexEvent writes the index entries and certificate registry record of an
+// event already stamped with its txID and timestamp.
+func (s *SmartContract) indexEvent(ctx contractapi.TransactionContextInterface, asset *Asset, event ProvenanceEvent) error {
+	if err := indexClientEvent(ctx, asset.AssetID, event, event.TxID); err != nil {
+		return err
+	}
+	if err := s.trackCertificate(ctx, asset, event, event.TxID); err != nil {
+		return err
+	}
+	if err := indexMaterialBatches(ctx, asset.AssetID, event); err != nil {
+		return err
+	}
+	if err := indexSupplier(ctx, asset.AssetID, event); err != nil {
+		return err
+	}
+	if err := indexMachine(ctx, asset.AssetID, event); err != nil {
+		return err
+	}
+	return indexInspectionResult(ctx, asset.AssetID, event)
+}
+
+// txTimestamp returns the transaction timestamp formatted as RFC3339 in UTC.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (string, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %w", err)
+	}
+	return timestamp.AsTime().UTC().Format(time.RFC3339), nil
+}
+
+// putAsset writes the asset to the world state under its ID.
+func (s *SmartContract) putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	err := upgradeAsset(asset)
+	if err != nil {
+		return err
+	}
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(asset.AssetID, assetJSON)
+}
+
+// CreateMaterialCertification creates the initial asset. hashAlgorithm names
+// the algorithm that produced offChainDataHash and defaults to sha256.
+// expiryTimestamp is the optional RFC3339 end of the material's shelf life,
+// after which it may not be printed from until recertified.
+func (s *SmartContract) CreateMaterialCertification(ctx contractapi.TransactionContextInterface, assetID string, materialType string, materialBatchID string, supplierID string, offChainDataHash string, hashAlgorithm string, expiryTimestamp string) error {
+	err := validateAssetID(assetID)
+	if err != nil {
+		return err
+	}
+	expiry, err := parseExpiry("expiryTimestamp", expiryTimestamp)
+	if err != nil {
+		return err
+	}
 	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return fmt.Errorf("failed to get client MSPID: %v", err)
+		return fmt.Errorf("failed to get client MSPID: %w", err)
 	}
 	exists, err := s.AssetExists(ctx, assetID)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return fmt.Errorf("the asset %s already exists", assetID)
+		return fmt.Errorf("%w: the asset %s already exists", ErrAssetExists, assetID)
 	}
 	// *** MODIFICATION: Initialize the full struct to ensure consistent schema ***
 	event := ProvenanceEvent{
-		EventType:       "MATERIAL_CERTIFICATION",
+		EventType:       EventMaterialCertification,
 		AgentID:         clientMSPID,
 		OffChainDataHash:  offChainDataHash,
+		HashAlgorithm:   hashAlgorithm,
 		MaterialType:    materialType,
 		MaterialBatchID: materialBatchID,
 		SupplierID:      supplierID,
@@ -93,39 +286,47 @@ func (s *SmartContract) CreateMaterialCertification(ctx contractapi.TransactionC
 		FinalTestResult:         "",
 		CertificateID:           "",
         OnChainDataPayload:      "",
-	}
-	txID, err := s.recordEvent(ctx, event)
-	if err != nil {
-		return err
+		ExpiryTimestamp:         expiry,
 	}
 	asset := Asset{
-		AssetID:             assetID,
-		Owner:               clientMSPID,
-		CurrentLifecycleStage: "MATERIAL_CERTIFIED",
-		HistoryTxIDs:        []string{txID},
-	}
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return err
+		AssetID:         assetID,
+		Owner:           clientMSPID,
+		HistoryTxIDs:    []string{},
+		ExpiryTimestamp: expiry,
 	}
-	return ctx.GetStub().PutState(assetID, assetJSON)
+	return s.recordAssetEvent(ctx, &asset, event, StageMaterialCertified)
 }
 
-// AddHistoryEvent adds a new generic event to an asset's history.
-func (s *SmartContract) AddHistoryEvent(ctx contractapi.TransactionContextInterface, assetID string, eventType string, offChainDataHash string) error {
+// AddHistoryEvent adds a new generic event to an asset's history. The event
+// type is the lifecycle stage the asset moves to and must be a transition the
+// lifecycle state machine allows from the asset's current stage. Only the
+// owner of the asset may add events, and events named after a production or
+// quality stage require the role attribute of that stage. An empty
+// hashAlgorithm defaults to sha256. It returns the txID that recorded the
+// event, or the one that already recorded clientEventID for the asset.
+func (s *SmartContract) AddHistoryEvent(ctx contractapi.TransactionContextInterface, assetID string, eventType string, offChainDataHash string, hashAlgorithm string, clientEventID string) (string, error) {
+    stage, err := parseLifecycleStage(ctx, eventType)
+    if err != nil {
+        return "", err
+    }
     asset, err := s.ReadAsset(ctx, assetID)
     if err != nil {
-        return err
+        return "", err
     }
-    clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+    clientMSPID, err := requireOwner(ctx, asset)
     if err != nil {
-        return fmt.Errorf("failed to get client MSPID: %v", err)
+        return "", err
+    }
+    if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+        return txID, err
     }
     // *** MODIFICATION: Initialize the full struct to ensure consistent schema ***
     event := ProvenanceEvent{
-        EventType:       eventType,
+        EventType:       EventType(stage),
         AgentID:         clientMSPID,
+        ClientEventID:   clientEventID,
         OffChainDataHash:  offChainDataHash,
+        HashAlgorithm:   hashAlgorithm,
 		MaterialType:    "", // Explicitly set other fields to empty
 		MaterialBatchID: "",
 		SupplierID:      "",
@@ -138,27 +339,22 @@ func (s *SmartContract) AddHistoryEvent(ctx contractapi.TransactionContextInterf
 		CertificateID:           "",
         OnChainDataPayload:      "",
     }
-    txID, err := s.recordEvent(ctx, event)
-    if err != nil {
-        return err
-    }
-    asset.CurrentLifecycleStage = eventType
-    asset.HistoryTxIDs = append(asset.HistoryTxIDs, txID)
-    assetJSON, err := json.Marshal(asset)
-    if err != nil {
-        return err
-    }
-    return ctx.GetStub().PutState(assetID, assetJSON)
+    return s.recordClientEvent(ctx, asset, event, stage)
 }
 
-// ReadAsset returns the asset stored in the world state.
+// ReadAsset returns the asset stored in the world state. Every transaction
+// taking an existing asset's ID goes through it, so the ID is validated here.
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+	err := validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
 	assetJSON, err := ctx.GetStub().GetState(assetID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
 	}
 	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", assetID)
+		return nil, fmt.Errorf("%w: the asset %s does not exist", ErrAssetNotFound, assetID)
 	}
 	var asset Asset
 	err = json.Unmarshal(assetJSON, &asset)
@@ -168,37 +364,151 @@ func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, a
 	return &asset, nil
 }
 
-// GetAssetHistory returns the full provenance history of an asset.
+// GetAssetHistory returns the full provenance history of an asset ordered by
+// timestamp then txID, with each transaction listed once. The txIDs whose
+// event could not be loaded are reported in Skipped, and amended events are
+// listed in Amendments so consumers can apply the corrections. Each event is
+// encoded as the typed event of its event type, so the array mixes event
+// shapes distinguished by eventType.
 func (s *SmartContract) GetAssetHistory(ctx contractapi.TransactionContextInterface, assetID string) (*HistoryResult, error) {
 	asset, err := s.ReadAsset(ctx, assetID)
 	if err != nil {
 		return nil, err
 	}
-	var history []ProvenanceEvent
+	events, skipped := loadAssetEvents(ctx, asset)
+	sortEvents(events)
+	result := HistoryResult{
+		Events:     events,
+		Skipped:    skipped,
+		Amendments: amendments(events),
+	}
+	return &result, nil
+}
+
+// assetEvents loads the events referenced by the asset's history, skipping any
+// that cannot be read.
+func assetEvents(ctx contractapi.TransactionContextInterface, asset *Asset) []ProvenanceEvent {
+	events, _ := loadAssetEvents(ctx, asset)
+	return events
+}
+
+// loadAssetEvents loads the events referenced by the asset's history in
+// history order, each txID once, and returns the txIDs whose event could not
+// be read.
+func loadAssetEvents(ctx contractapi.TransactionContextInterface, asset *Asset) ([]ProvenanceEvent, []string) {
+	history := []ProvenanceEvent{}
+	skipped := []string{}
+	seen := make(map[string]bool)
 	for _, txID := range asset.HistoryTxIDs {
-		eventKey := "EVENT_" + txID
-		eventJSON, err := ctx.GetStub().GetState(eventKey)
+		if seen[txID] {
+			continue
+		}
+		seen[txID] = true
+		eventJSON, err := readEventJSON(ctx, txID, asset.AssetID)
 		if err != nil || eventJSON == nil {
+			skipped = append(skipped, txID)
 			continue
 		}
 		var event ProvenanceEvent
 		err = json.Unmarshal(eventJSON, &event)
 		if err != nil {
+			skipped = append(skipped, txID)
 			continue
 		}
+		event.TxID = txID
 		history = append(history, event)
 	}
-	result := HistoryResult{
-		Events: history,
+	return history, skipped
+}
+
+// sortEvents orders events by timestamp, then txID. Timestamps that cannot be
+// parsed sort first.
+func sortEvents(events []ProvenanceEvent) {
+	times := make([]time.Time, len(events))
+	for i, event := range events {
+		times[i], _ = time.Parse(time.RFC3339, event.Timestamp)
 	}
-	return &result, nil
+	sort.Sort(eventsByTime{events: events, times: times})
+}
+
+// eventsByTime sorts events together with their parsed timestamps.
+type eventsByTime struct {
+	events []ProvenanceEvent
+	times  []time.Time
+}
+
+func (e eventsByTime) Len() int { return len(e.events) }
+
+func (e eventsByTime) Less(i, j int) bool {
+	if !e.times[i].Equal(e.times[j]) {
+		return e.times[i].Before(e.times[j])
+	}
+	return e.events[i].TxID < e.events[j].TxID
+}
+
+func (e eventsByTime) Swap(i, j int) {
+	e.events[i], e.events[j] = e.events[j], e.events[i]
+	e.times[i], e.times[j] = e.times[j], e.times[i]
+}
+
+// readEventJSON returns the stored event a transaction recorded for an asset,
+// falling back to its legacy keys until MigrateEventKeys has rekeyed it. It
+// returns nil when no such event exists.
+func readEventJSON(ctx contractapi.TransactionContextInterface, txID string, assetID string) ([]byte, error) {
+	key, err := eventKey(ctx, txID, assetID)
+	if err != nil {
+		return nil, err
+	}
+	eventJSON, err := ctx.GetStub().GetState(key)
+	if err != nil || eventJSON != nil {
+		return eventJSON, err
+	}
+	for _, legacyKey := range legacyEventKeys(txID, assetID) {
+		eventJSON, err = ctx.GetStub().GetState(legacyKey)
+		if err != nil || eventJSON != nil {
+			return eventJSON, err
+		}
+	}
+	return nil, nil
+}
+
+// getAllAssets returns every asset in the world state, skipping event records
+// still stored under legacy EVENT_ keys.
+func (s *SmartContract) getAllAssets(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
+	iterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	defer iterator.Close()
+
+	assets := []*Asset{}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate world state: %w", err)
+		}
+		if strings.HasPrefix(result.Key, "EVENT_") {
+			continue
+		}
+		var asset Asset
+		err = json.Unmarshal(result.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, &asset)
+	}
+	return assets, nil
 }
 
 // AssetExists returns true when asset with given ID exists in world state
 func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	err := validateAssetID(id)
+	if err != nil {
+		return false, err
+	}
 	assetJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
-		return false, fmt.Errorf("failed to read from world state: %v", err)
+		return false, fmt.Errorf("failed to read from world state: %w", err)
 	}
 	return assetJSON != nil, nil
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AmendmentPayload is the on-chain payload of an AMENDMENT event.
+type AmendmentPayload struct {
+	OriginalTxID string        `json:"originalTxID"`
+	Changes      []FieldChange `json:"changes"`
+	Reason       string        `json:"reason"`
+}
+
+// amendableEventFields lists, by JSON name, the recorded facts of an event an
+// amendment may correct. The identity, timing and chaining of an event cannot
+// be amended, off-chain hashes are corrected with SupersedeOffChainData, and
+// certificate IDs, expiries and payloads drive ledger state and are changed
+// through their dedicated transactions.
+var amendableEventFields = map[string]bool{
+	"materialType":            true,
+	"materialBatchID":         true,
+	"supplierID":              true,
+	"printJobID":              true,
+	"machineID":               true,
+	"materialUsedID":          true,
+	"primaryInspectionResult": true,
+	"testStandardApplied":     true,
+	"finalTestResult":         true,
+	"parentSerialNumber":      true,
+	"installationPosition":    true,
+	"failureMode":             true,
+	"serviceHours":            true,
+}
+
+// amendments maps the txID of each amended event to the txIDs of the
+// AMENDMENT events correcting it, in the order of events.
+func amendments(events []ProvenanceEvent) map[string][]string {
+	amended := make(map[string][]string)
+	for _, event := range events {
+		if event.EventType != EventAmendment {
+			continue
+		}
+		var payload AmendmentPayload
+		if json.Unmarshal([]byte(event.OnChainDataPayload), &payload) != nil {
+			continue
+		}
+		amended[payload.OriginalTxID] = append(amended[payload.OriginalTxID], event.TxID)
+	}
+	if len(amended) == 0 {
+		return nil
+	}
+	return amended
+}
+
+// AmendEvent corrects fields of the event recorded by originalTxID, such as a
+// mistyped machineID, by recording an AMENDMENT event that carries the before
+// and after value of each corrected field and the reason. The original event is
+// left untouched and amendments do not re-evaluate the asset's state.
+// correctedFieldsJSON is a JSON object keyed by event field name; only
+// amendable fields may be named. Only the MSP that recorded the original event
+// or the admin MSP may amend it. It returns the txID that recorded the
+// amendment, or the one that already recorded clientEventID for the asset.
+func (s *SmartContract) AmendEvent(ctx contractapi.TransactionContextInterface, assetID string, originalTxID string, correctedFieldsJSON string, reason string, clientEventID string) (string, error) {
+	if reason == "" {
+		return "", fmt.Errorf("%w: a reason is required", ErrInvalidArgument)
+	}
+	var corrected map[string]json.RawMessage
+	err := json.Unmarshal([]byte(correctedFieldsJSON), &corrected)
+	if err != nil {
+		return "", fmt.Errorf("%w: correctedFieldsJSON must be a JSON object: %v", ErrInvalidArgument, err)
+	}
+	if len(corrected) == 0 {
+		return "", fmt.Errorf("%w: correctedFieldsJSON must contain at least one field", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	original, err := historyEvent(ctx, asset, originalTxID)
+	if err != nil {
+		return "", err
+	}
+	if original.EventType == EventAmendment {
+		return "", fmt.Errorf("%w: the event %s is an amendment; amend the original event instead", ErrInvalidState, originalTxID)
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return "", err
+	}
+	if clientMSPID != original.AgentID && !admin {
+		return "", fmt.Errorf("%w: client from %s may not amend an event recorded by %s", ErrUnauthorized, clientMSPID, original.AgentID)
+	}
+
+	before, err := jsonFields(*original)
+	if err != nil {
+		return "", err
+	}
+	fields := make([]string, 0, len(corrected))
+	for field := range corrected {
+		if !amendableEventFields[field] {
+			return "", fmt.Errorf("%w: the event field %s cannot be amended", ErrInvalidArgument, field)
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	amended := *original
+	for _, field := range fields {
+		fieldJSON, err := json.Marshal(map[string]json.RawMessage{field: corrected[field]})
+		if err != nil {
+			return "", err
+		}
+		err = json.Unmarshal(fieldJSON, &amended)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid value for field %s: %v", ErrInvalidArgument, field, err)
+		}
+	}
+	after, err := jsonFields(amended)
+	if err != nil {
+		return "", err
+	}
+	var changes []FieldChange
+	for _, field := range fields {
+		if string(before[field]) != string(after[field]) {
+			changes = append(changes, FieldChange{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+	if len(changes) == 0 {
+		return "", fmt.Errorf("%w: the amendment does not change event %s", ErrInvalidArgument, originalTxID)
+	}
+
+	payload, err := json.Marshal(AmendmentPayload{
+		OriginalTxID: originalTxID,
+		Changes:      changes,
+		Reason:       reason,
+	})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventAmendment,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+	}
+	return s.recordClientEvent(ctx, asset, event, "")
+}
+
+// jsonFields returns the JSON encoding of each field of a record, such as an
+// event or an asset, keyed by field name.
+func jsonFields(record interface{}) (map[string]json.RawMessage, error) {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(recordJSON, &fields)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// anchorObjectType is the composite key namespace for anchor records.
+const anchorObjectType = "anchor"
+
+// lastAnchorConfig names the configuration entry holding the most recent anchor.
+const lastAnchorConfig = "lastAnchor"
+
+// eventSequenceConfig names the configuration entry holding the sequence
+// number the next transaction recording events is given.
+const eventSequenceConfig = "eventSequence"
+
+// eventSequenceIndex is the composite key object type of the anchor sequence:
+// an append-only record of every event, keyed by the sequence number of the
+// transaction that recorded it and holding the hash of the event as recorded.
+// The sequence follows commit order, which event timestamps do not, and the
+// records outlive the events themselves, so archiving or deleting events never
+// changes what was or will be anchored. Every transaction recording an event
+// reads and advances the sequence, so such transactions endorsed against the
+// same sequence number conflict at commit and one has to be resubmitted; this
+// serialization is the price of a gap-free commit order.
+const eventSequenceIndex = "eventSequence~sequence~txID~assetID"
+
+// AnchorDigest is a Merkle root over the events recorded after an anchor
+// sequence number, ready to be published on an external chain.
+type AnchorDigest struct {
+	SinceSequence int      `json:"sinceSequence"`
+	LastSequence  int      `json:"lastSequence"`
+	EventCount    int      `json:"eventCount"`
+	MerkleRoot    string   `json:"merkleRoot"`
+	TxIDs         []string `json:"txIDs"`
+}
+
+// AnchorRecord links an anchored digest to its external notarization.
+type AnchorRecord struct {
+	AnchorTxID    string `json:"anchorTxID"`
+	ExternalTxRef string `json:"externalTxRef"`
+	MerkleRoot    string `json:"merkleRoot"`
+	SinceSequence int    `json:"sinceSequence"`
+	LastSequence  int    `json:"lastSequence"`
+	EventCount    int    `json:"eventCount"`
+	AgentID       string `json:"agentID"`
+	AnchoredAt    string `json:"anchoredAt"`
+}
+
+// nextEventSequence returns the sequence number of the current transaction's
+// events: the committed value of the sequence, which starts at 1.
+func nextEventSequence(ctx contractapi.TransactionContextInterface) (int, error) {
+	value, err := getConfig(ctx, eventSequenceConfig)
+	if err != nil || value == nil {
+		return 1, err
+	}
+	sequence, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse event sequence %q: %w", value, err)
+	}
+	return sequence, nil
+}
+
+// sequenceEvent appends the event recorded as eventJSON to the anchor
+// sequence under the current transaction's sequence number, and advances the
+// sequence past it. Events recorded by one transaction share its number.
+func sequenceEvent(ctx contractapi.TransactionContextInterface, assetID string, eventJSON []byte) error {
+	sequence, err := nextEventSequence(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(eventSequenceIndex, []string{fmt.Sprintf("%020d", sequence), ctx.GetStub().GetTxID(), assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create event sequence key: %w", err)
+	}
+	hash := sha256.Sum256(eventJSON)
+	err = ctx.GetStub().PutState(key, hash[:])
+	if err != nil {
+		return fmt.Errorf("failed to put event sequence record: %w", err)
+	}
+	return putConfig(ctx, []byte(strconv.Itoa(sequence+1)), eventSequenceConfig)
+}
+
+// merkleRoot returns the hex encoded Merkle root of the given leaf hashes. An
+// odd node at any level is promoted unchanged; an empty tree has an empty root.
+func merkleRoot(leaves [][]byte) string {
+	if len(leaves) == 0 {
+		return ""
+	}
+	level := leaves
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			sum := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, sum[:])
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// getLastAnchor returns the most recent anchor record, or nil when none exists.
+func getLastAnchor(ctx contractapi.TransactionContextInterface) (*AnchorRecord, error) {
+	value, err := getConfig(ctx, lastAnchorConfig)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	var record AnchorRecord
+	err = json.Unmarshal(value, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// computeAnchorDigest builds the digest over the events of the anchor
+// sequence after sinceSequence, in sequence order and, within a transaction,
+// by asset ID. Only the sequence numbers in between are read, so the cost
+// grows with the events since the last anchor rather than the whole ledger.
+func computeAnchorDigest(ctx contractapi.TransactionContextInterface, sinceSequence int) (*AnchorDigest, error) {
+	next, err := nextEventSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sinceSequence < 0 || sinceSequence >= next {
+		return nil, fmt.Errorf("%w: the anchor sequence number %d does not exist, the last is %d", ErrNotFound, sinceSequence, next-1)
+	}
+	digest := AnchorDigest{SinceSequence: sinceSequence, LastSequence: sinceSequence, TxIDs: []string{}}
+	var hashes [][]byte
+	for sequence := sinceSequence + 1; sequence < next; sequence++ {
+		iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(eventSequenceIndex, []string{fmt.Sprintf("%020d", sequence)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the anchor sequence: %w", err)
+		}
+		for iterator.HasNext() {
+			result, err := iterator.Next()
+			if err != nil {
+				iterator.Close()
+				return nil, fmt.Errorf("failed to iterate the anchor sequence: %w", err)
+			}
+			_, keyParts, err := ctx.GetStub().SplitCompositeKey(result.Key)
+			if err != nil {
+				iterator.Close()
+				return nil, fmt.Errorf("failed to split event sequence key: %w", err)
+			}
+			digest.TxIDs = append(digest.TxIDs, keyParts[1])
+			hashes = append(hashes, result.Value)
+			digest.LastSequence = sequence
+		}
+		iterator.Close()
+	}
+	digest.EventCount = len(hashes)
+	digest.MerkleRoot = merkleRoot(hashes)
+	return &digest, nil
+}
+
+// ComputeAnchorDigest returns a Merkle root over every event recorded after
+// the anchor sequence number sinceSequence. Zero starts after the last
+// recorded anchor, or from the first event when nothing has been anchored
+// yet. Events recorded before the anchor sequence was introduced are not
+// part of it.
+func (s *SmartContract) ComputeAnchorDigest(ctx contractapi.TransactionContextInterface, sinceSequence int) (*AnchorDigest, error) {
+	if sinceSequence == 0 {
+		last, err := getLastAnchor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			sinceSequence = last.LastSequence
+		}
+	}
+	return computeAnchorDigest(ctx, sinceSequence)
+}
+
+// RecordAnchor stores the external reference under which a digest was
+// published. The digest must match the events recorded since the last anchor.
+// Only the admin MSP may record an anchor.
+func (s *SmartContract) RecordAnchor(ctx contractapi.TransactionContextInterface, externalTxRef string, digest string) error {
+	if externalTxRef == "" {
+		return fmt.Errorf("%w: externalTxRef must not be empty", ErrInvalidArgument)
+	}
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	sinceSequence := 0
+	last, err := getLastAnchor(ctx)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		sinceSequence = last.LastSequence
+	}
+	expected, err := computeAnchorDigest(ctx, sinceSequence)
+	if err != nil {
+		return err
+	}
+	if expected.EventCount == 0 {
+		return fmt.Errorf("%w: no events have been recorded since the last anchor", ErrInvalidState)
+	}
+	if expected.MerkleRoot != digest {
+		return fmt.Errorf("%w: digest %s does not match the events on record, expected %s", ErrInvalidArgument, digest, expected.MerkleRoot)
+	}
+	anchoredAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	record := AnchorRecord{
+		AnchorTxID:    ctx.GetStub().GetTxID(),
+		ExternalTxRef: externalTxRef,
+		MerkleRoot:    expected.MerkleRoot,
+		SinceSequence: sinceSequence,
+		LastSequence:  expected.LastSequence,
+		EventCount:    expected.EventCount,
+		AgentID:       clientMSPID,
+		AnchoredAt:    anchoredAt,
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(anchorObjectType, []string{record.AnchorTxID})
+	if err != nil {
+		return fmt.Errorf("failed to create anchor key: %w", err)
+	}
+	err = ctx.GetStub().PutState(key, recordJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put anchor state: %w", err)
+	}
+	return putConfig(ctx, recordJSON, lastAnchorConfig)
+}
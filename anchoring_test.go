@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestRecordAnchor(t *testing.T) {
+	s := new(SmartContract)
+	l := seedLedger(t)
+	var digest *AnchorDigest
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) (err error) {
+		digest, err = s.ComputeAnchorDigest(ctx, 0)
+		return err
+	})
+	if digest.SinceSequence != 0 || digest.EventCount == 0 {
+		t.Fatalf("first digest = %+v, want every seeded event", digest)
+	}
+
+	ctx, _ := l.context("", testSupplierMSP, nil)
+	if err := s.RecordAnchor(ctx, "ext-1", digest.MerkleRoot); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("RecordAnchor() by a non-admin = %v, want %v", err, ErrUnauthorized)
+	}
+	l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return s.RecordAnchor(ctx, "ext-1", digest.MerkleRoot)
+	})
+
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.SetAssetTags(ctx, "PART-1", `{"program":"next"}`, "")
+		return err
+	})
+	var next *AnchorDigest
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) (err error) {
+		next, err = s.ComputeAnchorDigest(ctx, 0)
+		return err
+	})
+	if next.SinceSequence != digest.LastSequence || next.EventCount != 1 {
+		t.Fatalf("digest after the anchor = %+v, want the one event after sequence %d", next, digest.LastSequence)
+	}
+}
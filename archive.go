@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ArchiveSummaryPayload is the on-chain payload of an ARCHIVED_SUMMARY event.
+// EventHashes lists the eventHash of every archived event in history order,
+// the leaves of MerkleRoot. LastArchivedHash is the hash the first remaining
+// event chains to. CustodyChain is the chain of custody recorded by the
+// archived events, starting with the asset's creator.
+type ArchiveSummaryPayload struct {
+	Before           string          `json:"before"`
+	Count            int             `json:"count"`
+	FirstTxID        string          `json:"firstTxID"`
+	LastTxID         string          `json:"lastTxID"`
+	MerkleRoot       string          `json:"merkleRoot"`
+	LastArchivedHash string          `json:"lastArchivedHash"`
+	EventHashes      []string        `json:"eventHashes"`
+	CustodyChain     []CustodyRecord `json:"custodyChain,omitempty"`
+}
+
+// ArchiveHistory compacts the leading events of an asset's history recorded
+// before beforeTimestamp: their records are removed from the world state and
+// their txIDs from the history, replaced by one ARCHIVED_SUMMARY event holding
+// their count, their hashes and the Merkle root over them. The removed records
+// stay in the ledger's blocks, and VerifyArchivedEvent proves one against the
+// summary. Since the hashes are stored in the summary payload, the number of
+// events archived at once is bound by the payload size limit. Only the owner
+// or the admin MSP may archive an asset's history.
+func (s *SmartContract) ArchiveHistory(ctx contractapi.TransactionContextInterface, assetID string, beforeTimestamp string) error {
+	before, err := time.Parse(time.RFC3339, beforeTimestamp)
+	if err != nil {
+		return fmt.Errorf("%w: beforeTimestamp must be an RFC3339 timestamp, got %q: %v", ErrInvalidArgument, beforeTimestamp, err)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return err
+	}
+	events, skipped := loadAssetEvents(ctx, asset)
+	if len(skipped) > 0 {
+		return fmt.Errorf("%w: the events of transactions %v of asset %s cannot be read", ErrInvalidState, skipped, assetID)
+	}
+
+	count := 0
+	for _, event := range events {
+		recorded, err := time.Parse(time.RFC3339, event.Timestamp)
+		if err != nil || !recorded.Before(before) {
+			break
+		}
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: the asset %s has no events recorded before %s", ErrInvalidState, assetID, beforeTimestamp)
+	}
+	archived := events[:count]
+
+	payload := ArchiveSummaryPayload{
+		Before:       beforeTimestamp,
+		Count:        count,
+		FirstTxID:    archived[0].TxID,
+		LastTxID:     archived[count-1].TxID,
+		EventHashes:  make([]string, 0, count),
+		CustodyChain: custodyChain(archived),
+	}
+	leaves := make([][]byte, 0, count)
+	for _, event := range archived {
+		hash, err := eventHash(event)
+		if err != nil {
+			return err
+		}
+		leaf, err := hex.DecodeString(hash)
+		if err != nil {
+			return err
+		}
+		payload.EventHashes = append(payload.EventHashes, hash)
+		leaves = append(leaves, leaf)
+		err = deleteEventRecord(ctx, event.TxID, assetID)
+		if err != nil {
+			return err
+		}
+	}
+	payload.MerkleRoot = merkleRoot(leaves)
+	payload.LastArchivedHash = payload.EventHashes[count-1]
+	if count < len(events) {
+		// The first remaining event chains to the hash of its own schema version.
+		payload.LastArchivedHash, err = chainedEventHash(archived[count-1], events[count].SchemaVersion)
+		if err != nil {
+			return err
+		}
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	remaining := []string{}
+	for _, event := range events[count:] {
+		remaining = append(remaining, event.TxID)
+	}
+	asset.HistoryTxIDs = remaining
+	event := ProvenanceEvent{
+		EventType:          EventArchivedSummary,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: string(payloadJSON),
+	}
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
+
+// archiveSummaries returns the payloads of the asset's ARCHIVED_SUMMARY events
+// keyed by txID.
+func archiveSummaries(events []ProvenanceEvent) map[string]ArchiveSummaryPayload {
+	summaries := make(map[string]ArchiveSummaryPayload)
+	for _, event := range events {
+		if event.EventType != EventArchivedSummary {
+			continue
+		}
+		var payload ArchiveSummaryPayload
+		if json.Unmarshal([]byte(event.OnChainDataPayload), &payload) == nil {
+			summaries[event.TxID] = payload
+		}
+	}
+	return summaries
+}
+
+// VerifyArchivedEvent reports whether eventJSON, an event as it was returned
+// before archival, is one of the events archived by the ARCHIVED_SUMMARY event
+// of summaryTxID, checking both its hash and the summary's Merkle root.
+func (s *SmartContract) VerifyArchivedEvent(ctx contractapi.TransactionContextInterface, assetID string, summaryTxID string, eventJSON string) (bool, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return false, err
+	}
+	summary, ok := archiveSummaries(assetEvents(ctx, asset))[summaryTxID]
+	if !ok {
+		return false, fmt.Errorf("%w: the asset %s has no archive summary %s", ErrNotFound, assetID, summaryTxID)
+	}
+	var event ProvenanceEvent
+	err = json.Unmarshal([]byte(eventJSON), &event)
+	if err != nil {
+		return false, fmt.Errorf("%w: eventJSON must be a provenance event: %v", ErrInvalidArgument, err)
+	}
+	hash, err := eventHash(event)
+	if err != nil {
+		return false, err
+	}
+	// Summaries recorded before canonical hashing hold legacy hashes.
+	legacyHash, err := legacyEventHash(event)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	leaves := make([][]byte, 0, len(summary.EventHashes))
+	for _, leafHash := range summary.EventHashes {
+		found = found || leafHash == hash || leafHash == legacyHash
+		leaf, err := hex.DecodeString(leafHash)
+		if err != nil {
+			return false, fmt.Errorf("%w: the archive summary %s holds an invalid hash", ErrInvalidState, summaryTxID)
+		}
+		leaves = append(leaves, leaf)
+	}
+	return found && merkleRoot(leaves) == summary.MerkleRoot, nil
+}
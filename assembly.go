@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AssemblyPayload is the on-chain payload of an ASSEMBLY event.
+type AssemblyPayload struct {
+	ProductAssetID    string   `json:"productAssetID"`
+	ComponentAssetIDs []string `json:"componentAssetIDs"`
+}
+
+// RecordAssembly records that productAssetID was assembled from the given
+// certified components, or components reclaimed by RecordDisassembly. In the genealogy the components become parents of the
+// product, as with LinkAssets, so the product's lineage reaches their material.
+// Each component is marked installed in the product and moves to INSTALLED,
+// so it cannot be assembled into another product. The caller must own the
+// product and every component.
+func (s *SmartContract) RecordAssembly(ctx contractapi.TransactionContextInterface, productAssetID string, componentAssetIDs []string, offChainDataHash string) error {
+	if len(componentAssetIDs) == 0 {
+		return fmt.Errorf("%w: at least one component is required", ErrInvalidArgument)
+	}
+	product, err := s.ReadAsset(ctx, productAssetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwner(ctx, product)
+	if err != nil {
+		return err
+	}
+	productAncestors, err := s.ancestorIDs(ctx, product)
+	if err != nil {
+		return err
+	}
+	alreadyParent := make(map[string]bool)
+	for _, id := range productAncestors {
+		alreadyParent[id] = true
+	}
+
+	seen := make(map[string]bool)
+	components := make([]*Asset, 0, len(componentAssetIDs))
+	for _, componentID := range componentAssetIDs {
+		if componentID == productAssetID {
+			return fmt.Errorf("%w: a product cannot be assembled from itself", ErrInvalidArgument)
+		}
+		if seen[componentID] {
+			return fmt.Errorf("%w: the component %s is listed more than once", ErrInvalidArgument, componentID)
+		}
+		seen[componentID] = true
+		if alreadyParent[componentID] {
+			return fmt.Errorf("%w: the component %s is already part of the lineage of %s", ErrInvalidState, componentID, productAssetID)
+		}
+		component, err := s.ReadAsset(ctx, componentID)
+		if err != nil {
+			return err
+		}
+		if _, err := requireOwner(ctx, component); err != nil {
+			return err
+		}
+		if component.Held {
+			return fmt.Errorf("%w: the component %s is on hold: %s", ErrInvalidState, componentID, component.HoldReason)
+		}
+		if component.InstalledIn != "" {
+			return fmt.Errorf("%w: the component %s is already consumed into %s", ErrInvalidState, componentID, component.InstalledIn)
+		}
+		if component.CurrentLifecycleStage != StageCertified && component.CurrentLifecycleStage != StageDisassembled {
+			return fmt.Errorf("%w: the component %s is in stage %s, not CERTIFIED or DISASSEMBLED", ErrInvalidState, componentID, component.CurrentLifecycleStage)
+		}
+		ancestors, err := s.ancestorIDs(ctx, component)
+		if err != nil {
+			return err
+		}
+		for _, id := range ancestors {
+			if id == productAssetID {
+				return fmt.Errorf("%w: assembling %s into %s would create a cycle", ErrInvalidState, componentID, productAssetID)
+			}
+		}
+		components = append(components, component)
+	}
+
+	payload, err := json.Marshal(AssemblyPayload{
+		ProductAssetID:    productAssetID,
+		ComponentAssetIDs: componentAssetIDs,
+	})
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventAssembly,
+		AgentID:            clientMSPID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payload),
+		ParentSerialNumber: productAssetID,
+	}
+	for _, component := range components {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(installedInIndex, []string{productAssetID, component.AssetID})
+		if err != nil {
+			return fmt.Errorf("failed to create installation index key: %w", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put installation index: %w", err)
+		}
+		component.InstalledIn = productAssetID
+		component.ChildAssetIDs = append(component.ChildAssetIDs, productAssetID)
+		err = s.appendAssetEvent(ctx, component, event, StageInstalled)
+		if err != nil {
+			return err
+		}
+	}
+	product.ParentAssetIDs = append(product.ParentAssetIDs, componentAssetIDs...)
+	err = s.appendAssetEvent(ctx, product, event, "")
+	if err != nil {
+		return err
+	}
+	return emitChaincodeEvent(ctx, event.EventType, append([]string{productAssetID}, componentAssetIDs...)...)
+}
+
+// RecordDisassembly records that componentAssetID was reclaimed from
+// productAssetID, the inverse of RecordAssembly. The genealogy link between
+// them is removed and the component moves to DISASSEMBLED, from which it may
+// be assembled or installed again or sent back to inspection. DISASSEMBLY
+// events are recorded on both. The caller must own the product and the
+// component.
+func (s *SmartContract) RecordDisassembly(ctx contractapi.TransactionContextInterface, productAssetID string, componentAssetID string, offChainDataHash string) error {
+	product, err := s.ReadAsset(ctx, productAssetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwner(ctx, product)
+	if err != nil {
+		return err
+	}
+	component, err := s.ReadAsset(ctx, componentAssetID)
+	if err != nil {
+		return err
+	}
+	if _, err := requireOwner(ctx, component); err != nil {
+		return err
+	}
+	linked := false
+	for _, id := range product.ParentAssetIDs {
+		if id == componentAssetID {
+			linked = true
+			break
+		}
+	}
+	if !linked || component.InstalledIn != productAssetID {
+		return fmt.Errorf("%w: the asset %s is not a component of %s", ErrInvalidState, componentAssetID, productAssetID)
+	}
+
+	payload, err := json.Marshal(AssemblyPayload{
+		ProductAssetID:    productAssetID,
+		ComponentAssetIDs: []string{componentAssetID},
+	})
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventDisassembly,
+		AgentID:            clientMSPID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payload),
+		ParentSerialNumber: productAssetID,
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(installedInIndex, []string{productAssetID, componentAssetID})
+	if err != nil {
+		return fmt.Errorf("failed to create installation index key: %w", err)
+	}
+	err = ctx.GetStub().DelState(indexKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete installation index: %w", err)
+	}
+	component.InstalledIn = ""
+	component.ChildAssetIDs = withoutID(component.ChildAssetIDs, productAssetID)
+	err = s.appendAssetEvent(ctx, component, event, StageDisassembled)
+	if err != nil {
+		return err
+	}
+	product.ParentAssetIDs = withoutID(product.ParentAssetIDs, componentAssetID)
+	err = s.appendAssetEvent(ctx, product, event, "")
+	if err != nil {
+		return err
+	}
+	return emitChaincodeEvent(ctx, event.EventType, productAssetID, componentAssetID)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// requiredAttributeConfig names the configuration entries overriding the
+// certificate attribute required to record each event type.
+const requiredAttributeConfig = "requiredAttribute"
+
+// roleAttribute is the client certificate attribute carrying a user's role.
+const roleAttribute = "am.role"
+
+// AttributeRequirement is a client certificate attribute and the value it must hold.
+type AttributeRequirement struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+}
+
+// requiredAttributes lists the attribute each event type requires of the
+// submitting user unless reconfigured. Any other event type requires none.
+// The generic events named after a production or quality stage, recorded
+// through AddHistoryEvent or BulkTransition, require the same role as the
+// dedicated transaction recording that stage.
+var requiredAttributes = map[EventType]AttributeRequirement{
+	EventPrintJob:       {Attribute: roleAttribute, Value: "operator"},
+	EventReprint:        {Attribute: roleAttribute, Value: "operator"},
+	EventPostProcessing: {Attribute: roleAttribute, Value: "operator"},
+	EventInspection:     {Attribute: roleAttribute, Value: "qa"},
+	EventFinalTest:      {Attribute: roleAttribute, Value: "qa"},
+	EventPrinted:        {Attribute: roleAttribute, Value: "operator"},
+	EventPostProcessed:  {Attribute: roleAttribute, Value: "operator"},
+	EventInspected:      {Attribute: roleAttribute, Value: "qa"},
+	EventTested:         {Attribute: roleAttribute, Value: "qa"},
+	EventCertified:      {Attribute: roleAttribute, Value: "qa"},
+}
+
+// requireAttribute returns an error unless the caller's certificate carries
+// attr with the given value.
+func requireAttribute(ctx contractapi.TransactionContextInterface, attr string, value string) error {
+	actual, found, err := ctx.GetClientIdentity().GetAttributeValue(attr)
+	if err != nil {
+		return fmt.Errorf("failed to read client attribute %s: %w", attr, err)
+	}
+	if !found {
+		return fmt.Errorf("%w: client certificate has no %s attribute, %s required", ErrUnauthorized, attr, value)
+	}
+	if actual != value {
+		return fmt.Errorf("%w: client attribute %s is %s, %s required", ErrUnauthorized, attr, actual, value)
+	}
+	return nil
+}
+
+// attributeRequirement returns the attribute required to record eventType,
+// honouring any override stored in the ledger configuration, or nil when none is.
+func attributeRequirement(ctx contractapi.TransactionContextInterface, eventType EventType) (*AttributeRequirement, error) {
+	value, err := getConfig(ctx, requiredAttributeConfig, string(eventType))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		requirement, ok := requiredAttributes[eventType]
+		if !ok {
+			return nil, nil
+		}
+		return &requirement, nil
+	}
+	var requirement AttributeRequirement
+	err = json.Unmarshal(value, &requirement)
+	if err != nil {
+		return nil, err
+	}
+	if requirement.Attribute == "" {
+		return nil, nil
+	}
+	return &requirement, nil
+}
+
+// attributeValidator rejects an event submitted by a user lacking the
+// certificate attribute its event type requires.
+type attributeValidator struct{}
+
+func (attributeValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	requirement, err := attributeRequirement(ctx, pending.Event.EventType)
+	if err != nil || requirement == nil {
+		return err
+	}
+	return requireAttribute(ctx, requirement.Attribute, requirement.Value)
+}
+
+// SetRequiredAttribute configures the client certificate attribute and value
+// required to record events of eventType. An empty attribute lifts the
+// requirement. Only the admin MSP may change it.
+func (s *SmartContract) SetRequiredAttribute(ctx contractapi.TransactionContextInterface, eventType string, attribute string, value string) error {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	if attribute != "" && value == "" {
+		return fmt.Errorf("%w: value must not be empty when an attribute is required", ErrInvalidArgument)
+	}
+	err = requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	requirementJSON, err := json.Marshal(AttributeRequirement{Attribute: attribute, Value: value})
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, requirementJSON, requiredAttributeConfig, string(typ))
+}
+
+// GetRequiredAttribute returns the attribute required to record events of
+// eventType; an empty attribute means none is required.
+func (s *SmartContract) GetRequiredAttribute(ctx contractapi.TransactionContextInterface, eventType string) (*AttributeRequirement, error) {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	requirement, err := attributeRequirement(ctx, typ)
+	if err != nil {
+		return nil, err
+	}
+	if requirement == nil {
+		return &AttributeRequirement{}, nil
+	}
+	return requirement, nil
+}
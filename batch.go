@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// MaterialCertificationInput is one entry of a batch material certification.
+type MaterialCertificationInput struct {
+	AssetID          string  `json:"assetID"`
+	MaterialType     string  `json:"materialType"`
+	MaterialBatchID  string  `json:"materialBatchID"`
+	SupplierID       string  `json:"supplierID"`
+	Quantity         float64 `json:"quantity"`
+	OffChainDataHash string  `json:"offChainDataHash"`
+	HashAlgorithm    string  `json:"hashAlgorithm"`
+	ExpiryTimestamp  string  `json:"expiryTimestamp"`
+}
+
+// CreateMaterialCertificationBatch creates one asset per entry of assetsJSON, a
+// JSON array of MaterialCertificationInput, and returns the created asset IDs.
+// Every entry is validated before anything is written, so a single invalid
+// entry fails the whole batch. All assets share the transaction's txID.
+func (s *SmartContract) CreateMaterialCertificationBatch(ctx contractapi.TransactionContextInterface, assetsJSON string) ([]string, error) {
+	var inputs []MaterialCertificationInput
+	err := json.Unmarshal([]byte(assetsJSON), &inputs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: assetsJSON must be a JSON array of certification inputs: %v", ErrInvalidArgument, err)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: assetsJSON must contain at least one entry", ErrInvalidArgument)
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	pending := make([]PendingEvent, 0, len(inputs))
+	for i, input := range inputs {
+		err := validateAssetID(input.AssetID)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if input.MaterialType == "" || input.MaterialBatchID == "" {
+			return nil, fmt.Errorf("%w: entry %d: materialType and materialBatchID are required", ErrInvalidArgument, i)
+		}
+		if input.Quantity < 0 {
+			return nil, fmt.Errorf("%w: entry %d: quantity must not be negative", ErrInvalidArgument, i)
+		}
+		expiry, err := parseExpiry("expiryTimestamp", input.ExpiryTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if seen[input.AssetID] {
+			return nil, fmt.Errorf("%w: entry %d: the asset %s appears more than once", ErrInvalidArgument, i, input.AssetID)
+		}
+		seen[input.AssetID] = true
+		exists, err := s.AssetExists(ctx, input.AssetID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: entry %d: the asset %s already exists", ErrAssetExists, i, input.AssetID)
+		}
+		entry := PendingEvent{
+			Asset: &Asset{
+				AssetID:           input.AssetID,
+				Owner:             clientMSPID,
+				HistoryTxIDs:      []string{},
+				Quantity:          input.Quantity,
+				RemainingQuantity: input.Quantity,
+				ExpiryTimestamp:   expiry,
+			},
+			Event: &ProvenanceEvent{
+				EventType:        EventMaterialCertification,
+				AgentID:          clientMSPID,
+				OffChainDataHash: input.OffChainDataHash,
+				HashAlgorithm:    input.HashAlgorithm,
+				MaterialType:     input.MaterialType,
+				MaterialBatchID:  input.MaterialBatchID,
+				SupplierID:       input.SupplierID,
+				ExpiryTimestamp:  expiry,
+			},
+			NextStage: StageMaterialCertified,
+		}
+		err = runValidators(ctx, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		pending = append(pending, entry)
+	}
+
+	assetIDs := make([]string, 0, len(pending))
+	for _, entry := range pending {
+		err = s.appendAssetEvent(ctx, entry.Asset, *entry.Event, entry.NextStage)
+		if err != nil {
+			return nil, err
+		}
+		assetIDs = append(assetIDs, entry.Asset.AssetID)
+	}
+	err = emitChaincodeEvent(ctx, EventMaterialCertification, assetIDs...)
+	if err != nil {
+		return nil, err
+	}
+	return assetIDs, nil
+}
+
+// BulkTransition moves every asset of assetIDs to the lifecycle stage named by
+// eventType, recording one generic event per asset as AddHistoryEvent does,
+// all sharing the transaction's txID and offChainDataHash. The caller must own
+// every asset. Every asset is validated against the lifecycle and the other
+// event validators before anything is written, so a single asset in an
+// illegal source state fails the whole batch with an error naming it. It
+// returns the txID that recorded the batch, or the one that already recorded
+// clientEventID for the first asset.
+func (s *SmartContract) BulkTransition(ctx contractapi.TransactionContextInterface, assetIDs []string, eventType string, offChainDataHash string, clientEventID string) (string, error) {
+	if len(assetIDs) == 0 {
+		return "", fmt.Errorf("%w: assetIDs must contain at least one asset", ErrInvalidArgument)
+	}
+	stage, err := parseLifecycleStage(ctx, eventType)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	if txID, err := priorClientEvent(ctx, assetIDs[0], clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+
+	seen := make(map[string]bool)
+	pending := make([]PendingEvent, 0, len(assetIDs))
+	for _, assetID := range assetIDs {
+		if seen[assetID] {
+			return "", fmt.Errorf("%w: the asset %s appears more than once", ErrInvalidArgument, assetID)
+		}
+		seen[assetID] = true
+		asset, err := s.ReadAsset(ctx, assetID)
+		if err != nil {
+			return "", fmt.Errorf("asset %s: %w", assetID, err)
+		}
+		_, err = requireOwner(ctx, asset)
+		if err != nil {
+			return "", fmt.Errorf("asset %s: %w", assetID, err)
+		}
+		entry := PendingEvent{
+			Asset: asset,
+			Event: &ProvenanceEvent{
+				EventType:        EventType(stage),
+				AgentID:          clientMSPID,
+				ClientEventID:    clientEventID,
+				OffChainDataHash: offChainDataHash,
+			},
+			NextStage: stage,
+		}
+		err = runValidators(ctx, &entry)
+		if err != nil {
+			return "", fmt.Errorf("asset %s: %w", assetID, err)
+		}
+		pending = append(pending, entry)
+	}
+
+	for _, entry := range pending {
+		err = s.appendAssetEvent(ctx, entry.Asset, *entry.Event, entry.NextStage)
+		if err != nil {
+			return "", err
+		}
+	}
+	err = emitChaincodeEvent(ctx, EventType(stage), assetIDs...)
+	if err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
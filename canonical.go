@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// canonicalJSON returns the canonical JSON encoding of value used for hashing:
+// object keys are sorted, and fields holding a JSON zero value (null, "", 0,
+// false, [] or {}) are omitted at every depth, so the bytes depend only on the
+// logical content and not on struct field order or omitempty tags. Strings
+// are not HTML-escaped.
+func canonicalJSON(value interface{}) ([]byte, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(valueJSON))
+	decoder.UseNumber()
+	var decoded interface{}
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		return nil, err
+	}
+	var canonical bytes.Buffer
+	encoder := json.NewEncoder(&canonical)
+	encoder.SetEscapeHTML(false)
+	// Maps are encoded with sorted keys.
+	err = encoder.Encode(pruneZeroValues(decoded))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(canonical.Bytes(), []byte("\n")), nil
+}
+
+// pruneZeroValues drops the object fields of a decoded JSON value that hold a
+// zero value, recursively. Array elements are kept to preserve positions.
+func pruneZeroValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		pruned := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			field = pruneZeroValues(field)
+			if !isZeroJSON(field) {
+				pruned[key] = field
+			}
+		}
+		return pruned
+	case []interface{}:
+		pruned := make([]interface{}, len(v))
+		for i, element := range v {
+			pruned[i] = pruneZeroValues(element)
+		}
+		return pruned
+	}
+	return value
+}
+
+// isZeroJSON reports whether a decoded JSON value is a zero value.
+func isZeroJSON(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case json.Number:
+		number, err := v.Float64()
+		return err == nil && number == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
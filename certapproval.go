@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// certificationPolicyConfig names the configuration entry holding the
+// certification approval policy.
+const certificationPolicyConfig = "certificationPolicy"
+
+// CertificationPolicy is the approval an asset needs before it may become
+// CERTIFIED. Approvals from distinct MSPs add up their weight until Threshold
+// is reached. Without Weights every MSP weighs 1; with Weights only the listed
+// MSPs may approve. A zero Threshold lifts the policy.
+type CertificationPolicy struct {
+	Threshold int            `json:"threshold"`
+	Weights   map[string]int `json:"weights,omitempty" metadata:",optional"`
+}
+
+// CertificationApproval is one MSP's sign-off on a certification proposal.
+type CertificationApproval struct {
+	MSPID     string `json:"mspID"`
+	Weight    int    `json:"weight"`
+	TxID      string `json:"txID"`
+	Timestamp string `json:"timestamp"`
+}
+
+// CertificationProposal is a pending certification of an asset awaiting
+// approval. ProposalID is the txID of the proposal. ApprovedAt is set once
+// the approvals reached the threshold and the asset was certified; the
+// proposal is then kept on the asset as the record of its approvals.
+type CertificationProposal struct {
+	ProposalID       string                  `json:"proposalID"`
+	ProposedBy       string                  `json:"proposedBy"`
+	ProposedAt       string                  `json:"proposedAt"`
+	CertificateID    string                  `json:"certificateID"`
+	Standard         string                  `json:"standard,omitempty" metadata:",optional"`
+	OffChainDataHash string                  `json:"offChainDataHash"`
+	Approvals        []CertificationApproval `json:"approvals"`
+	ApprovedAt       string                  `json:"approvedAt,omitempty" metadata:",optional"`
+}
+
+// CertificationApprovalPayload is the on-chain payload of the
+// CERTIFICATION_APPROVED event of each approval short of the threshold, and of
+// the CERTIFIED event recorded by the approval reaching it.
+type CertificationApprovalPayload struct {
+	ProposalID     string                  `json:"proposalID"`
+	ApprovedWeight int                     `json:"approvedWeight"`
+	Threshold      int                     `json:"threshold"`
+	Approvals      []CertificationApproval `json:"approvals"`
+}
+
+// CertificationWithdrawalPayload is the on-chain payload of a
+// CERTIFICATION_WITHDRAWN event.
+type CertificationWithdrawalPayload struct {
+	ProposalID string `json:"proposalID"`
+	Reason     string `json:"reason"`
+}
+
+// certificationPolicy returns the configured certification policy, or nil
+// when certification needs no approval.
+func certificationPolicy(ctx contractapi.TransactionContextInterface) (*CertificationPolicy, error) {
+	value, err := getConfig(ctx, certificationPolicyConfig)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	var policy CertificationPolicy
+	err = json.Unmarshal(value, &policy)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Threshold == 0 {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+// approvalWeight returns the weight of an approval by mspID under the policy.
+func (policy *CertificationPolicy) approvalWeight(mspID string) int {
+	if policy.Weights == nil {
+		return 1
+	}
+	return policy.Weights[mspID]
+}
+
+// approvedWeight returns the total weight of the proposal's approvals.
+func (proposal *CertificationProposal) approvedWeight() int {
+	weight := 0
+	for _, approval := range proposal.Approvals {
+		weight += approval.Weight
+	}
+	return weight
+}
+
+// certificationApprovalValidator holds back any transition to CERTIFIED while
+// a certification policy is configured and the asset's proposal has not been
+// approved up to its threshold.
+type certificationApprovalValidator struct{}
+
+func (certificationApprovalValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if pending.NextStage != StageCertified {
+		return nil
+	}
+	policy, err := certificationPolicy(ctx)
+	if err != nil || policy == nil {
+		return err
+	}
+	proposal := pending.Asset.CertificationProposal
+	if proposal == nil {
+		return fmt.Errorf("%w: the asset %s must be certified through ProposeCertification and ApproveCertification", ErrInvalidState, pending.Asset.AssetID)
+	}
+	if weight := proposal.approvedWeight(); weight < policy.Threshold {
+		return fmt.Errorf("%w: the certification of asset %s has approvals of weight %d, %d required", ErrInvalidState, pending.Asset.AssetID, weight, policy.Threshold)
+	}
+	return nil
+}
+
+// SetCertificationPolicy configures the approval certification requires:
+// threshold is the approval weight to reach and weightsJSON an optional JSON
+// object mapping each approving MSP to its weight. A zero threshold lifts the
+// policy, so assets may again move to CERTIFIED directly. Only the admin MSP
+// may change it.
+func (s *SmartContract) SetCertificationPolicy(ctx contractapi.TransactionContextInterface, threshold int, weightsJSON string) error {
+	if threshold < 0 {
+		return fmt.Errorf("%w: threshold must not be negative, got %d", ErrInvalidArgument, threshold)
+	}
+	policy := CertificationPolicy{Threshold: threshold}
+	if weightsJSON != "" {
+		err := json.Unmarshal([]byte(weightsJSON), &policy.Weights)
+		if err != nil {
+			return fmt.Errorf("%w: weightsJSON must be a JSON object of integer weights: %v", ErrInvalidArgument, err)
+		}
+		total := 0
+		for mspID, weight := range policy.Weights {
+			if weight <= 0 {
+				return fmt.Errorf("%w: the weight of %s must be positive, got %d", ErrInvalidArgument, mspID, weight)
+			}
+			total += weight
+		}
+		if total < threshold {
+			return fmt.Errorf("%w: the weights add up to %d, below the threshold %d", ErrInvalidArgument, total, threshold)
+		}
+	}
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, policyJSON, certificationPolicyConfig)
+}
+
+// GetCertificationPolicy returns the certification approval policy; a zero
+// threshold means certification needs no approval.
+func (s *SmartContract) GetCertificationPolicy(ctx contractapi.TransactionContextInterface) (*CertificationPolicy, error) {
+	policy, err := certificationPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return &CertificationPolicy{}, nil
+	}
+	return policy, nil
+}
+
+// ProposeCertification opens a certification proposal for an asset that meets
+// every certification prerequisite, naming the certificate to issue, the
+// standard it is issued against and the off-chain evidence, and records a
+// CERTIFICATION_PROPOSED event. It returns the proposal ID approvers pass to
+// ApproveCertification. Only the owner of the asset or the admin MSP may
+// propose, and the certificate must be one the asset may still be issued, so
+// the approvals cannot fail on it later. An asset has at most one open
+// proposal, which WithdrawCertificationProposal closes.
+func (s *SmartContract) ProposeCertification(ctx contractapi.TransactionContextInterface, assetID string, certificateID string, standard string, offChainDataHash string) (string, error) {
+	if certificateID == "" {
+		return "", fmt.Errorf("%w: certificateID must not be empty", ErrInvalidArgument)
+	}
+	if standard == "" {
+		return "", fmt.Errorf("%w: standard must not be empty", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	if proposal := asset.CertificationProposal; proposal != nil && proposal.ApprovedAt == "" {
+		return "", fmt.Errorf("%w: the asset %s already has the open certification proposal %s", ErrInvalidState, assetID, proposal.ProposalID)
+	}
+	certified := &PendingEvent{
+		Asset:     asset,
+		Event:     &ProvenanceEvent{EventType: EventCertified, CertificateID: certificateID, TestStandardApplied: standard},
+		NextStage: StageCertified,
+	}
+	for _, validator := range []Validator{certificationValidator{}, lifecycleValidator{}, revokedCertificateValidator{}, uniqueCertificateValidator{}} {
+		err = validator.Validate(ctx, certified)
+		if err != nil {
+			return "", err
+		}
+	}
+	if offChainDataHash == "" {
+		required, err := isOffChainHashRequired(ctx, EventCertified)
+		if err != nil {
+			return "", err
+		}
+		if required {
+			return "", fmt.Errorf("%w: events of type %s require an offChainDataHash", ErrInvalidArgument, EventCertified)
+		}
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	proposal := &CertificationProposal{
+		ProposalID:       ctx.GetStub().GetTxID(),
+		ProposedBy:       clientMSPID,
+		ProposedAt:       timestamp,
+		CertificateID:    certificateID,
+		Standard:         standard,
+		OffChainDataHash: offChainDataHash,
+		Approvals:        []CertificationApproval{},
+	}
+	payload, err := json.Marshal(proposal)
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:           EventCertificationProposed,
+		AgentID:             clientMSPID,
+		OffChainDataHash:    offChainDataHash,
+		TestStandardApplied: standard,
+		OnChainDataPayload:  string(payload),
+	}
+	asset.CertificationProposal = proposal
+	err = s.recordAssetEvent(ctx, asset, event, "")
+	if err != nil {
+		return "", err
+	}
+	return proposal.ProposalID, nil
+}
+
+// WithdrawCertificationProposal closes the open certification proposal
+// proposalID of an asset without certifying it, recording a
+// CERTIFICATION_WITHDRAWN event with the reason, so a new proposal may be
+// opened. Only the owner of the asset or the admin MSP may withdraw it.
+func (s *SmartContract) WithdrawCertificationProposal(ctx contractapi.TransactionContextInterface, assetID string, proposalID string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("%w: reason must not be empty", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return err
+	}
+	proposal := asset.CertificationProposal
+	if proposal == nil || proposal.ApprovedAt != "" {
+		return fmt.Errorf("%w: the asset %s has no open certification proposal", ErrNotFound, assetID)
+	}
+	if proposal.ProposalID != proposalID {
+		return fmt.Errorf("%w: %s is not the open certification proposal %s of asset %s", ErrInvalidArgument, proposalID, proposal.ProposalID, assetID)
+	}
+	payload, err := json.Marshal(CertificationWithdrawalPayload{ProposalID: proposalID, Reason: reason})
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventCertificationWithdrawn,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: string(payload),
+	}
+	asset.CertificationProposal = nil
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
+
+// ApproveCertification adds the caller's MSP approval to the open
+// certification proposal approvalID of an asset. Each MSP approves once.
+// Approvals short of the policy threshold record a CERTIFICATION_APPROVED
+// event; the approval reaching it records the CERTIFIED event carrying the
+// proposed certificate and moves the asset to CERTIFIED. Without a configured
+// policy a single approval certifies the asset.
+func (s *SmartContract) ApproveCertification(ctx contractapi.TransactionContextInterface, assetID string, approvalID string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	proposal := asset.CertificationProposal
+	if proposal == nil || proposal.ApprovedAt != "" {
+		return fmt.Errorf("%w: the asset %s has no open certification proposal", ErrNotFound, assetID)
+	}
+	if proposal.ProposalID != approvalID {
+		return fmt.Errorf("%w: %s is not the open certification proposal %s of asset %s", ErrInvalidArgument, approvalID, proposal.ProposalID, assetID)
+	}
+	policy, err := certificationPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		policy = &CertificationPolicy{Threshold: 1}
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	for _, approval := range proposal.Approvals {
+		if approval.MSPID == clientMSPID {
+			return fmt.Errorf("%w: %s already approved the certification of asset %s", ErrInvalidState, clientMSPID, assetID)
+		}
+	}
+	weight := policy.approvalWeight(clientMSPID)
+	if weight == 0 {
+		return fmt.Errorf("%w: client from %s is not a certification approver", ErrUnauthorized, clientMSPID)
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	proposal.Approvals = append(proposal.Approvals, CertificationApproval{
+		MSPID:     clientMSPID,
+		Weight:    weight,
+		TxID:      ctx.GetStub().GetTxID(),
+		Timestamp: timestamp,
+	})
+	approvedWeight := proposal.approvedWeight()
+	payload, err := json.Marshal(CertificationApprovalPayload{
+		ProposalID:     proposal.ProposalID,
+		ApprovedWeight: approvedWeight,
+		Threshold:      policy.Threshold,
+		Approvals:      proposal.Approvals,
+	})
+	if err != nil {
+		return err
+	}
+	if approvedWeight < policy.Threshold {
+		event := ProvenanceEvent{
+			EventType:          EventCertificationApproved,
+			AgentID:            clientMSPID,
+			OnChainDataPayload: string(payload),
+		}
+		return s.recordAssetEvent(ctx, asset, event, "")
+	}
+	proposal.ApprovedAt = timestamp
+	event := ProvenanceEvent{
+		EventType:           EventCertified,
+		AgentID:             clientMSPID,
+		OffChainDataHash:    proposal.OffChainDataHash,
+		CertificateID:       proposal.CertificateID,
+		TestStandardApplied: proposal.Standard,
+		OnChainDataPayload:  string(payload),
+	}
+	return s.recordAssetEvent(ctx, asset, event, StageCertified)
+}
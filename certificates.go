@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// certificateObjectType is the composite key namespace for certificate records.
+const certificateObjectType = "certificate"
+
+// RevocationCode classifies why a certificate was revoked.
+type RevocationCode string
+
+const (
+	RevocationNonConformance  RevocationCode = "NON_CONFORMANCE"
+	RevocationTestInvalidated RevocationCode = "TEST_INVALIDATED"
+	RevocationIssuedInError   RevocationCode = "ISSUED_IN_ERROR"
+	RevocationSuperseded      RevocationCode = "SUPERSEDED"
+	RevocationRecall          RevocationCode = "RECALL"
+	RevocationOther           RevocationCode = "OTHER"
+)
+
+// revocationCodes lists the accepted revocation codes.
+var revocationCodes = map[RevocationCode]bool{
+	RevocationNonConformance:  true,
+	RevocationTestInvalidated: true,
+	RevocationIssuedInError:   true,
+	RevocationSuperseded:      true,
+	RevocationRecall:          true,
+	RevocationOther:           true,
+}
+
+// CertificateRecord tracks who issued a certificate and whether it was revoked.
+// Standard and DocumentHash, the digest of the certificate document, are set
+// for certificates issued by a CERTIFIED event that carries them.
+type CertificateRecord struct {
+	CertificateID    string         `json:"certificateID"`
+	AssetID          string         `json:"assetID"`
+	IssuerMSPID      string         `json:"issuerMSPID"`
+	IssueTxID        string         `json:"issueTxID"`
+	IssuedAt         string         `json:"issuedAt,omitempty" metadata:",optional"`
+	Standard         string         `json:"standard,omitempty" metadata:",optional"`
+	DocumentHash     string         `json:"documentHash,omitempty" metadata:",optional"`
+	Revoked          bool           `json:"revoked"`
+	RevocationCode   RevocationCode `json:"revocationCode,omitempty" metadata:",optional"`
+	RevocationReason string         `json:"revocationReason"`
+	RevokedAt        string         `json:"revokedAt"`
+}
+
+// CertificateValidity is the answer to a certificate validity check.
+type CertificateValidity struct {
+	CertificateID    string         `json:"certificateID"`
+	AssetID          string         `json:"assetID"`
+	Valid            bool           `json:"valid"`
+	Revoked          bool           `json:"revoked"`
+	RevocationCode   RevocationCode `json:"revocationCode,omitempty" metadata:",optional"`
+	RevocationReason string         `json:"revocationReason"`
+	RevokedAt        string         `json:"revokedAt"`
+}
+
+// RevocationPayload is the on-chain payload of a CERTIFICATE_REVOKED event.
+type RevocationPayload struct {
+	Code   RevocationCode `json:"code"`
+	Reason string         `json:"reason,omitempty"`
+}
+
+// readCertificate returns the certificate record, or nil when it does not exist.
+func readCertificate(ctx contractapi.TransactionContextInterface, certificateID string) (*CertificateRecord, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(certificateObjectType, []string{certificateID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate key: %w", err)
+	}
+	recordJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+	var record CertificateRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// putCertificate writes the certificate record to the world state.
+func putCertificate(ctx contractapi.TransactionContextInterface, record *CertificateRecord) error {
+	key, err := ctx.GetStub().CreateCompositeKey(certificateObjectType, []string{record.CertificateID})
+	if err != nil {
+		return fmt.Errorf("failed to create certificate key: %w", err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, recordJSON)
+}
+
+// revokedCertificateValidator refuses any event that re-uses a revoked certificate ID.
+type revokedCertificateValidator struct{}
+
+func (revokedCertificateValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if pending.Event.CertificateID == "" {
+		return nil
+	}
+	record, err := readCertificate(ctx, pending.Event.CertificateID)
+	if err != nil {
+		return err
+	}
+	if record != nil && record.Revoked {
+		return fmt.Errorf("%w: the certificate %s has been revoked and cannot be reused", ErrInvalidState, pending.Event.CertificateID)
+	}
+	return nil
+}
+
+// uniqueCertificateValidator refuses an event that carries a certificate ID
+// already issued for another asset, keeping certificate IDs globally unique.
+type uniqueCertificateValidator struct{}
+
+func (uniqueCertificateValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if pending.Event.CertificateID == "" {
+		return nil
+	}
+	record, err := readCertificate(ctx, pending.Event.CertificateID)
+	if err != nil {
+		return err
+	}
+	if record != nil && record.AssetID != pending.Asset.AssetID {
+		return fmt.Errorf("%w: the certificate %s is already issued for asset %s", ErrInvalidState, record.CertificateID, record.AssetID)
+	}
+	return nil
+}
+
+// trackCertificate registers the certificate carried by an event the first
+// time it is seen and makes it the asset's certificate. A different
+// certificate the asset held until then is superseded. A CERTIFIED event
+// issuing a certificate already registered, as by the asset's final test,
+// completes its record with the standard and document hash.
+func (s *SmartContract) trackCertificate(ctx contractapi.TransactionContextInterface, asset *Asset, event ProvenanceEvent, txID string) error {
+	if event.CertificateID == "" {
+		return nil
+	}
+	record, err := readCertificate(ctx, event.CertificateID)
+	if err != nil {
+		return err
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if event.EventType != EventCertificateRevoked && asset.CertificateID != event.CertificateID {
+		if asset.CertificateID != "" {
+			err = supersedeCertificate(ctx, asset.CertificateID, event.CertificateID, timestamp)
+			if err != nil {
+				return err
+			}
+		}
+		asset.CertificateID = event.CertificateID
+		asset.CertificateRevoked = false
+	}
+	if record != nil {
+		if event.EventType != EventCertified {
+			return nil
+		}
+		record.Standard = event.TestStandardApplied
+		record.DocumentHash = event.OffChainDataHash
+		return putCertificate(ctx, record)
+	}
+	record = &CertificateRecord{
+		CertificateID: event.CertificateID,
+		AssetID:       asset.AssetID,
+		IssuerMSPID:   event.AgentID,
+		IssueTxID:     txID,
+		IssuedAt:      timestamp,
+	}
+	if event.EventType == EventCertified {
+		record.Standard = event.TestStandardApplied
+		record.DocumentHash = event.OffChainDataHash
+	}
+	return putCertificate(ctx, record)
+}
+
+// supersedeCertificate revokes the certificate replaced by newCertificateID
+// with reason code SUPERSEDED, unless it is already revoked.
+func supersedeCertificate(ctx contractapi.TransactionContextInterface, certificateID string, newCertificateID string, timestamp string) error {
+	record, err := readCertificate(ctx, certificateID)
+	if err != nil || record == nil || record.Revoked {
+		return err
+	}
+	record.Revoked = true
+	record.RevocationCode = RevocationSuperseded
+	record.RevocationReason = fmt.Sprintf("superseded by certificate %s", newCertificateID)
+	record.RevokedAt = timestamp
+	return putCertificate(ctx, record)
+}
+
+// IssueCertificate certifies an asset that passed its final test, recording a
+// CERTIFIED event that issues certificateID against standard and moving the
+// asset to CERTIFIED. pdfHash is the digest of the certificate document, in
+// the format of an offChainDataHash, against which VerifyCertificate checks
+// copies presented to auditors. certificateID may be the certificate
+// registered by the asset's final test, but not one issued for another asset;
+// a different certificate the asset held is superseded. Every certification
+// prerequisite must be met, and under a certification policy the asset is
+// certified through ProposeCertification instead. Only the owner or the admin
+// MSP may issue a certificate. It returns the txID that recorded the
+// certification, or the one that already recorded clientEventID for the asset.
+func (s *SmartContract) IssueCertificate(ctx contractapi.TransactionContextInterface, assetID string, certificateID string, standard string, pdfHash string, clientEventID string) (string, error) {
+	if certificateID == "" {
+		return "", fmt.Errorf("%w: certificateID must not be empty", ErrInvalidArgument)
+	}
+	if standard == "" {
+		return "", fmt.Errorf("%w: standard must not be empty", ErrInvalidArgument)
+	}
+	if pdfHash == "" {
+		return "", fmt.Errorf("%w: pdfHash must not be empty", ErrInvalidArgument)
+	}
+	err := checkDigest("pdfHash", pdfHash)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	record, err := readCertificate(ctx, certificateID)
+	if err != nil {
+		return "", err
+	}
+	if record != nil && record.AssetID != asset.AssetID {
+		return "", fmt.Errorf("%w: the certificate %s is already issued for asset %s", ErrInvalidState, certificateID, record.AssetID)
+	}
+	event := ProvenanceEvent{
+		EventType:           EventCertified,
+		AgentID:             clientMSPID,
+		ClientEventID:       clientEventID,
+		OffChainDataHash:    pdfHash,
+		TestStandardApplied: standard,
+		CertificateID:       certificateID,
+	}
+	return s.recordClientEvent(ctx, asset, event, StageCertified)
+}
+
+// CertificateVerification is the result of VerifyCertificate. Matches reports
+// whether the provided hash equals the document hash recorded at issuance,
+// and Valid whether the certificate also was not revoked.
+type CertificateVerification struct {
+	CertificateID    string         `json:"certificateID"`
+	AssetID          string         `json:"assetID"`
+	Standard         string         `json:"standard,omitempty" metadata:",optional"`
+	IssuerMSPID      string         `json:"issuerMSPID"`
+	IssuedAt         string         `json:"issuedAt,omitempty" metadata:",optional"`
+	Matches          bool           `json:"matches"`
+	Valid            bool           `json:"valid"`
+	Revoked          bool           `json:"revoked"`
+	RevocationCode   RevocationCode `json:"revocationCode,omitempty" metadata:",optional"`
+	RevocationReason string         `json:"revocationReason,omitempty" metadata:",optional"`
+}
+
+// VerifyCertificate lets an auditor confirm that a certificate document
+// matches the chain: providedHash, the digest of the paper or PDF certificate
+// presented, is compared with the digest recorded when certificateID was
+// issued. Digests compare by algorithm and value, so "sha256:<digest>" matches
+// a bare sha256 digest. A certificate issued without a document hash never
+// matches.
+func (s *SmartContract) VerifyCertificate(ctx contractapi.TransactionContextInterface, certificateID string, providedHash string) (*CertificateVerification, error) {
+	if providedHash == "" {
+		return nil, fmt.Errorf("%w: providedHash must not be empty", ErrInvalidArgument)
+	}
+	err := checkDigest("providedHash", providedHash)
+	if err != nil {
+		return nil, err
+	}
+	providedAlgorithm, providedDigest, _ := resolveOffChainHash("", providedHash)
+	record, err := readCertificate(ctx, certificateID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: the certificate %s does not exist", ErrNotFound, certificateID)
+	}
+	matches := false
+	if record.DocumentHash != "" {
+		algorithm, digest, err := resolveOffChainHash("", record.DocumentHash)
+		matches = err == nil && algorithm == providedAlgorithm && digest == providedDigest
+	}
+	return &CertificateVerification{
+		CertificateID:    record.CertificateID,
+		AssetID:          record.AssetID,
+		Standard:         record.Standard,
+		IssuerMSPID:      record.IssuerMSPID,
+		IssuedAt:         record.IssuedAt,
+		Matches:          matches,
+		Valid:            matches && !record.Revoked,
+		Revoked:          record.Revoked,
+		RevocationCode:   record.RevocationCode,
+		RevocationReason: record.RevocationReason,
+	}, nil
+}
+
+// RevokeCertificate revokes a certificate, records the reason code and reason
+// in a CERTIFICATE_REVOKED event and moves the asset it was issued for to the
+// CERTIFICATE_REVOKED stage. reasonCode is one of NON_CONFORMANCE,
+// TEST_INVALIDATED, ISSUED_IN_ERROR, SUPERSEDED, RECALL or OTHER; the free-text
+// reason is optional except with OTHER. Only the MSP that issued the
+// certificate or the admin MSP may revoke it.
+func (s *SmartContract) RevokeCertificate(ctx contractapi.TransactionContextInterface, certificateID string, reasonCode string, reason string) error {
+	code := RevocationCode(reasonCode)
+	if !revocationCodes[code] {
+		return fmt.Errorf("%w: unknown revocation reason code %q", ErrInvalidArgument, reasonCode)
+	}
+	if code == RevocationOther && reason == "" {
+		return fmt.Errorf("%w: a revocation reason is required with reason code %s", ErrInvalidArgument, RevocationOther)
+	}
+	record, err := readCertificate(ctx, certificateID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return fmt.Errorf("%w: the certificate %s does not exist", ErrNotFound, certificateID)
+	}
+	if record.Revoked {
+		return fmt.Errorf("%w: the certificate %s is already revoked", ErrInvalidState, record.CertificateID)
+	}
+	asset, err := s.ReadAsset(ctx, record.AssetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if clientMSPID != record.IssuerMSPID && !admin {
+		return fmt.Errorf("%w: client from %s is not allowed to revoke certificate %s issued by %s", ErrUnauthorized, clientMSPID, record.CertificateID, record.IssuerMSPID)
+	}
+	payload, err := json.Marshal(RevocationPayload{Code: code, Reason: reason})
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventCertificateRevoked,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: string(payload),
+		CertificateID:      record.CertificateID,
+	}
+	if asset.CertificateID == record.CertificateID {
+		asset.CertificateRevoked = true
+	}
+	err = s.recordAssetEvent(ctx, asset, event, StageCertificateRevoked)
+	if err != nil {
+		return err
+	}
+	revokedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	record.Revoked = true
+	record.RevocationCode = code
+	record.RevocationReason = reason
+	record.RevokedAt = revokedAt
+	return putCertificate(ctx, record)
+}
+
+// GetCertificateValidity reports whether a certificate is still valid.
+func (s *SmartContract) GetCertificateValidity(ctx contractapi.TransactionContextInterface, certificateID string) (*CertificateValidity, error) {
+	record, err := readCertificate(ctx, certificateID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: the certificate %s does not exist", ErrNotFound, certificateID)
+	}
+	return &CertificateValidity{
+		CertificateID:    record.CertificateID,
+		AssetID:          record.AssetID,
+		Valid:            !record.Revoked,
+		Revoked:          record.Revoked,
+		RevocationCode:   record.RevocationCode,
+		RevocationReason: record.RevocationReason,
+		RevokedAt:        record.RevokedAt,
+	}, nil
+}
+
+// GetAssetByCertificate resolves a certificate ID to the asset it was issued
+// for. The asset's CertificateRevoked flag reports whether it was revoked.
+func (s *SmartContract) GetAssetByCertificate(ctx contractapi.TransactionContextInterface, certificateID string) (*Asset, error) {
+	record, err := readCertificate(ctx, certificateID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: the certificate %s does not exist", ErrNotFound, certificateID)
+	}
+	return s.ReadAsset(ctx, record.AssetID)
+}
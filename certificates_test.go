@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIssueCertificateRequiresOwner(t *testing.T) {
+	s := new(SmartContract)
+	l := seedLedger(t)
+	ctx, stub := l.context("", "OtherMSP", qa)
+	_, err := s.IssueCertificate(ctx, "PART-1", "CERT-1", "ASTM F2924", testHash, "")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("IssueCertificate() by another MSP = %v, want %v", err, ErrUnauthorized)
+	}
+	if len(stub.writes) != 0 {
+		t.Fatalf("rejected certificate wrote %v", stub.writes)
+	}
+
+	ctx, _ = l.context("", testSupplierMSP, qa)
+	_, err = s.IssueCertificate(ctx, "PART-1", "CERT-1", "ASTM F2924", testHash, "")
+	if err != nil {
+		t.Fatalf("IssueCertificate() by the owner = %v, want no error", err)
+	}
+}
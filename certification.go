@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// failingResults are the inspection and test results treated as a failure.
+var failingResults = map[string]bool{
+	"FAIL":     true,
+	"FAILED":   true,
+	"REJECTED": true,
+}
+
+// isFailingResult reports whether an inspection or test result is a failure.
+func isFailingResult(result string) bool {
+	return failingResults[strings.ToUpper(strings.TrimSpace(result))]
+}
+
+// isPassingResult reports whether a recorded inspection or test result is a pass.
+func isPassingResult(result string) bool {
+	return qualityResults[strings.ToUpper(strings.TrimSpace(result))] == ResultPass
+}
+
+// certificationBlockers lists the certification prerequisites the asset has not
+// met yet. An empty list means the asset may be certified. Only the typed PASS
+// result of the latest INSPECTION and FINAL_TEST events counts: generic
+// INSPECTED and TESTED events carry no result and pass nothing.
+func certificationBlockers(asset *Asset, events []ProvenanceEvent) []string {
+	inspected, tested, certified := false, false, false
+	for _, event := range events {
+		switch event.EventType {
+		case EventInspection:
+			inspected = isPassingResult(event.PrimaryInspectionResult)
+		case EventFinalTest:
+			tested = isPassingResult(event.FinalTestResult)
+		case EventCertified:
+			certified = true
+		}
+	}
+
+	var blockers []string
+	if asset.Held {
+		blockers = append(blockers, "asset is on hold")
+	}
+	if asset.Recalled {
+		blockers = append(blockers, "asset is recalled")
+	}
+	if asset.Failed {
+		blockers = append(blockers, "asset failed in service")
+	}
+	if asset.CertificateRevoked {
+		blockers = append(blockers, "certificate of the asset is revoked")
+	}
+	if len(asset.OpenNCRs) > 0 {
+		blockers = append(blockers, fmt.Sprintf("open non-conformances %s", strings.Join(asset.OpenNCRs, ", ")))
+	}
+	if certified {
+		blockers = append(blockers, "asset is already certified")
+	}
+	if !inspected {
+		blockers = append(blockers, "no passed inspection")
+	}
+	if !tested {
+		blockers = append(blockers, "no passed final test")
+	}
+	return blockers
+}
+
+// certificationValidator holds back any transition to CERTIFIED until every
+// certification prerequisite is met.
+type certificationValidator struct{}
+
+func (certificationValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if pending.NextStage != StageCertified {
+		return nil
+	}
+	blockers := certificationBlockers(pending.Asset, assetEvents(ctx, pending.Asset))
+	if len(blockers) > 0 {
+		return fmt.Errorf("%w: the asset %s cannot be certified: %s", ErrInvalidState, pending.Asset.AssetID, strings.Join(blockers, "; "))
+	}
+	return nil
+}
+
+// GetAssetsReadyForCertification returns the assets that meet every
+// certification prerequisite but have not been certified yet. Only assets in
+// stage TESTED, read from the stage index, can be certified.
+func (s *SmartContract) GetAssetsReadyForCertification(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
+	assets, err := s.GetAssetsByStage(ctx, string(StageTested))
+	if err != nil {
+		return nil, err
+	}
+	ready := []*Asset{}
+	for _, asset := range assets {
+		if len(certificationBlockers(asset, assetEvents(ctx, asset))) == 0 {
+			ready = append(ready, asset)
+		}
+	}
+	return ready, nil
+}
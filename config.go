@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// configObjectType is the composite key namespace holding contract configuration.
+// Composite keys keep configuration out of the asset key range.
+const configObjectType = "config"
+
+// adminMSPConfig names the configuration entry holding the consortium admin MSP.
+const adminMSPConfig = "adminMSPID"
+
+// getConfig returns the raw configuration value stored under name, or nil when unset.
+func getConfig(ctx contractapi.TransactionContextInterface, name ...string) ([]byte, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %w", err)
+	}
+	value, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from world state: %w", err)
+	}
+	return value, nil
+}
+
+// isAdmin reports whether the caller belongs to the configured consortium admin
+// MSP. Nobody is admin while no admin MSP has been configured.
+func isAdmin(ctx contractapi.TransactionContextInterface) (bool, error) {
+	adminMSPID, err := getConfig(ctx, adminMSPConfig)
+	if err != nil {
+		return false, err
+	}
+	if adminMSPID == nil {
+		return false, nil
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	return clientMSPID == string(adminMSPID), nil
+}
+
+// putConfig stores a raw configuration value under name.
+func putConfig(ctx contractapi.TransactionContextInterface, value []byte, name ...string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, name)
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %w", err)
+	}
+	return ctx.GetStub().PutState(key, value)
+}
+
+// requireAdmin returns an error unless the caller belongs to the admin MSP.
+func requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("%w: only the admin MSP may perform this operation", ErrUnauthorized)
+	}
+	return nil
+}
+
+// seedConfig stores value under name unless an entry already exists, so
+// re-running the bootstrap never clobbers configuration changed since.
+func seedConfig(ctx contractapi.TransactionContextInterface, value []byte, name ...string) error {
+	current, err := getConfig(ctx, name...)
+	if err != nil || current != nil {
+		return err
+	}
+	return putConfig(ctx, value, name...)
+}
+
+// seedDefaults writes the built-in defaults of the lifecycle, terminal stages,
+// off-chain hash requirements, required attributes and payload size limit
+// into the ledger configuration, skipping every entry already present. The
+// defaults in code still apply to any entry missing from state, so seeding
+// only makes the effective configuration visible on the ledger.
+func seedDefaults(ctx contractapi.TransactionContextInterface) error {
+	for fromStage, toStages := range lifecycleTransitions {
+		for _, toStage := range toStages {
+			if err := seedConfig(ctx, []byte{0x00}, transitionConfig, string(fromStage), string(toStage)); err != nil {
+				return err
+			}
+		}
+	}
+	for stage, terminal := range terminalStages {
+		if err := seedConfig(ctx, []byte(strconv.FormatBool(terminal)), terminalStageConfig, string(stage)); err != nil {
+			return err
+		}
+	}
+	for eventType, required := range offChainHashRequired {
+		if err := seedConfig(ctx, []byte(strconv.FormatBool(required)), offChainHashConfig, string(eventType)); err != nil {
+			return err
+		}
+	}
+	for eventType, requirement := range requiredAttributes {
+		value, err := json.Marshal(requirement)
+		if err != nil {
+			return err
+		}
+		if err := seedConfig(ctx, value, requiredAttributeConfig, string(eventType)); err != nil {
+			return err
+		}
+	}
+	return seedConfig(ctx, []byte(strconv.Itoa(defaultMaxPayloadSize)), maxPayloadSizeConfig)
+}
+
+// InitLedger is the init function of the chaincode, to be invoked when it is
+// deployed or upgraded, for instance with --init-required. It sets the
+// consortium admin MSP and seeds the default configuration, leaving existing
+// entries as they are, so it can be re-run after every upgrade to add
+// configuration introduced by the new version. Once an admin MSP is set, only
+// that admin may run it again.
+func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface, adminMSPID string) error {
+	if adminMSPID == "" {
+		return fmt.Errorf("%w: adminMSPID must not be empty", ErrInvalidArgument)
+	}
+	current, err := getConfig(ctx, adminMSPConfig)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		err = requireAdmin(ctx)
+		if err != nil {
+			return fmt.Errorf("the ledger is already initialized: %w", err)
+		}
+	}
+	err = seedDefaults(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte(adminMSPID), adminMSPConfig)
+}
+
+// ChangeAdmin hands the admin role over to newAdminMSPID. Only the current
+// admin MSP may change it.
+func (s *SmartContract) ChangeAdmin(ctx contractapi.TransactionContextInterface, newAdminMSPID string) error {
+	if newAdminMSPID == "" {
+		return fmt.Errorf("%w: newAdminMSPID must not be empty", ErrInvalidArgument)
+	}
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte(newAdminMSPID), adminMSPConfig)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// OwnershipTransferPayload is the on-chain payload of an OWNERSHIP_TRANSFER
+// event, recorded when the recipient accepts a transfer proposal.
+// PreviousCustodian is set when the transfer also moved physical custody away
+// from a separate custodian.
+type OwnershipTransferPayload struct {
+	ProposalID         string `json:"proposalID"`
+	PreviousOwner      string `json:"previousOwner"`
+	NewOwner           string `json:"newOwner"`
+	CustodyTransferred bool   `json:"custodyTransferred,omitempty"`
+	PreviousCustodian  string `json:"previousCustodian,omitempty"`
+}
+
+// CustodyTransferPayload is the on-chain payload of a CUSTODY_TRANSFER event.
+type CustodyTransferPayload struct {
+	PreviousCustodian string `json:"previousCustodian"`
+	NewCustodian      string `json:"newCustodian"`
+}
+
+// currentHolder returns the MSP physically holding the asset: its custodian,
+// or its owner when no separate custodian is recorded.
+func currentHolder(asset *Asset) string {
+	if asset.Custodian != "" {
+		return asset.Custodian
+	}
+	return asset.Owner
+}
+
+// setOwner makes newOwner the owner of the asset. A custodian that becomes the
+// owner is no longer recorded separately, and a pending transfer proposed by
+// the previous owner lapses.
+func setOwner(asset *Asset, newOwner string) {
+	asset.Owner = newOwner
+	if asset.Custodian == newOwner {
+		asset.Custodian = ""
+	}
+	asset.PendingTransfer = nil
+}
+
+// CustodyRecord is one link in an asset's chain of custody: the period during
+// which Owner owned the asset and, when set, Custodian held it on the owner's
+// behalf. EndTime is empty for the current link.
+type CustodyRecord struct {
+	Owner     string `json:"owner"`
+	Custodian string `json:"custodian,omitempty" metadata:",optional"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+	TxID      string `json:"txID"`
+}
+
+// GetCustodyChain returns the ordered chain of custody of an asset, starting
+// with its creator and followed by one record per ownership transfer and per
+// custody transfer, including those of archived events.
+func (s *SmartContract) GetCustodyChain(ctx contractapi.TransactionContextInterface, assetID string) ([]CustodyRecord, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return custodyChain(assetEvents(ctx, asset)), nil
+}
+
+// custodyChain builds the chain of custody recorded by events, an asset's
+// history in order. An ARCHIVED_SUMMARY event stands for the chain of the
+// events it archived, so the creator is still found once the creation event
+// has been archived.
+func custodyChain(events []ProvenanceEvent) []CustodyRecord {
+	chain := []CustodyRecord{}
+	for i, event := range events {
+		var current CustodyRecord
+		if len(chain) > 0 {
+			current = chain[len(chain)-1]
+		}
+		var records []CustodyRecord
+		switch {
+		case event.EventType == EventArchivedSummary:
+			var summary ArchiveSummaryPayload
+			if json.Unmarshal([]byte(event.OnChainDataPayload), &summary) != nil {
+				continue
+			}
+			records = summary.CustodyChain
+		case i == 0:
+			records = []CustodyRecord{{Owner: event.AgentID, StartTime: event.Timestamp, TxID: event.TxID}}
+		case event.EventType == EventOwnershipTransfer:
+			var transfer OwnershipTransferPayload
+			if json.Unmarshal([]byte(event.OnChainDataPayload), &transfer) != nil || transfer.NewOwner == "" {
+				continue
+			}
+			custodian := current.Custodian
+			if transfer.CustodyTransferred || custodian == transfer.NewOwner {
+				custodian = ""
+			}
+			records = []CustodyRecord{{Owner: transfer.NewOwner, Custodian: custodian, StartTime: event.Timestamp, TxID: event.TxID}}
+		case event.EventType == EventCustodyTransfer:
+			var handoff CustodyTransferPayload
+			if json.Unmarshal([]byte(event.OnChainDataPayload), &handoff) != nil || handoff.NewCustodian == "" {
+				continue
+			}
+			custodian := handoff.NewCustodian
+			if custodian == current.Owner {
+				custodian = ""
+			}
+			records = []CustodyRecord{{Owner: current.Owner, Custodian: custodian, StartTime: event.Timestamp, TxID: event.TxID}}
+		}
+		for _, record := range records {
+			if len(chain) > 0 && chain[len(chain)-1].EndTime == "" {
+				chain[len(chain)-1].EndTime = record.StartTime
+			}
+			chain = append(chain, record)
+		}
+	}
+	return chain
+}
+
+// OwnershipEntry records when an owner acquired an asset.
+type OwnershipEntry struct {
+	Owner     string `json:"owner"`
+	Timestamp string `json:"timestamp"`
+	TxID      string `json:"txID"`
+}
+
+// GetOwnershipHistory returns every owner an asset ever had, in order, with
+// the time and transaction in which each acquired it. An asset that was never
+// transferred returns just its creator; custody transfers that left the owner
+// unchanged are skipped.
+func (s *SmartContract) GetOwnershipHistory(ctx contractapi.TransactionContextInterface, assetID string) ([]OwnershipEntry, error) {
+	chain, err := s.GetCustodyChain(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]OwnershipEntry, 0, len(chain))
+	for _, record := range chain {
+		if len(history) > 0 && history[len(history)-1].Owner == record.Owner {
+			continue
+		}
+		history = append(history, OwnershipEntry{
+			Owner:     record.Owner,
+			Timestamp: record.StartTime,
+			TxID:      record.TxID,
+		})
+	}
+	return history, nil
+}
+
+// TransferOwnership proposes transferring an asset to a new owner MSP, as
+// ProposeTransfer does, and returns the proposal ID; ownership only changes
+// once newOwner accepts through AcceptTransfer. Only the current owner may
+// transfer the asset. With withCustody the asset is handed over outright and
+// newOwner also takes physical custody, as when parts pass from the powder
+// supplier to the service bureau to the OEM. Otherwise a separately recorded
+// custodian keeps physical custody, and custody only follows ownership when
+// none is recorded.
+func (s *SmartContract) TransferOwnership(ctx contractapi.TransactionContextInterface, assetID string, newOwner string, withCustody bool) (string, error) {
+	return s.proposeTransfer(ctx, assetID, newOwner, !withCustody)
+}
+
+// TransferAsset hands an asset over to newOwner outright, as TransferOwnership
+// with custody does. Transfers now need the recipient's confirmation, so
+// TransferAsset only proposes the transfer and returns the proposal ID;
+// ownership and custody change, and an OWNERSHIP_TRANSFER event is recorded,
+// once newOwner accepts it through AcceptTransfer. Only the current owner may
+// transfer the asset.
+func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, assetID string, newOwner string) (string, error) {
+	return s.TransferOwnership(ctx, assetID, newOwner, true)
+}
+
+// TransferCustody hands physical custody of an asset to newCustodian without
+// changing its legal owner, as in consignment, and records a CUSTODY_TRANSFER
+// event. The current holder or the owner may transfer custody. Handing custody
+// back to the owner clears the separate custodian. It returns the txID that
+// recorded the transfer, or the one that already recorded clientEventID for
+// the asset.
+func (s *SmartContract) TransferCustody(ctx contractapi.TransactionContextInterface, assetID string, newCustodian string, clientEventID string) (string, error) {
+	if newCustodian == "" {
+		return "", fmt.Errorf("%w: newCustodian must not be empty", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	// A retry is recognised before the holder check, as the first attempt may
+	// have handed custody away from the caller.
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	holder := currentHolder(asset)
+	if clientMSPID != holder && clientMSPID != asset.Owner {
+		return "", fmt.Errorf("%w: client from %s is neither the owner nor the custodian of asset %s", ErrUnauthorized, clientMSPID, assetID)
+	}
+	if newCustodian == holder {
+		return "", fmt.Errorf("%w: the asset %s is already held by %s", ErrInvalidState, assetID, newCustodian)
+	}
+	payload, err := json.Marshal(CustodyTransferPayload{
+		PreviousCustodian: holder,
+		NewCustodian:      newCustodian,
+	})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventCustodyTransfer,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+	}
+	asset.Custodian = newCustodian
+	if newCustodian == asset.Owner {
+		asset.Custodian = ""
+	}
+	return s.recordClientEvent(ctx, asset, event, "")
+}
+
+// GetCurrentHolder returns the MSP physically holding the asset: its
+// custodian, or its owner when custody was never handed to another party.
+func (s *SmartContract) GetCurrentHolder(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	return currentHolder(asset), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestCustodyChainFollowsCustodyAndOwnership(t *testing.T) {
+	s := new(SmartContract)
+	l := seedLedger(t)
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.TransferCustody(ctx, "PART-1", "CarrierMSP", "")
+		return err
+	})
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.TransferAsset(ctx, "PART-1", "OEMMSP")
+		return err
+	})
+	l.submit(t, "OEMMSP", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return s.AcceptTransfer(ctx, "PART-1")
+	})
+
+	ctx, _ := l.context("", "OEMMSP", nil)
+	chain, err := s.GetCustodyChain(ctx, "PART-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links [][2]string
+	for _, record := range chain {
+		links = append(links, [2]string{record.Owner, record.Custodian})
+	}
+	want := [][2]string{{testSupplierMSP, ""}, {testSupplierMSP, "CarrierMSP"}, {"OEMMSP", ""}}
+	if !reflect.DeepEqual(links, want) {
+		t.Fatalf("GetCustodyChain() = %v, want %v", links, want)
+	}
+
+	history, err := s.GetOwnershipHistory(ctx, "PART-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Owner != "OEMMSP" {
+		t.Fatalf("GetOwnershipHistory() = %+v, want the creator and OEMMSP", history)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// DeleteAsset removes an asset from the world state together with every index
+// entry pointing to it. Only the owner may delete it, and assets other assets
+// were made from cannot be deleted; held assets and assets in a terminal
+// stage, such as scrapped ones, can. A DELETE event is recorded first so the
+// deletion itself stays auditable. The asset's event records and the service
+// failures reported against its material batches are kept: they are the audit
+// trail, and the events remain reachable through GetAssetStateHistory, which
+// returns the txIDs of the deleted asset.
+func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, assetID string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwner(ctx, asset)
+	if err != nil {
+		return err
+	}
+	if len(asset.ChildAssetIDs) > 0 {
+		return fmt.Errorf("%w: the asset %s cannot be deleted while assets %v descend from it", ErrInvalidState, assetID, asset.ChildAssetIDs)
+	}
+	events := assetEvents(ctx, asset)
+	event := ProvenanceEvent{
+		EventType: EventDelete,
+		AgentID:   clientMSPID,
+	}
+	err = s.recordAssetEvent(ctx, asset, event, "")
+	if err != nil {
+		return err
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	event.TxID = ctx.GetStub().GetTxID()
+	event.Timestamp = timestamp
+	err = deleteEventIndexes(ctx, assetID, append(events, event))
+	if err != nil {
+		return err
+	}
+
+	for _, parentID := range asset.ParentAssetIDs {
+		parent, err := s.ReadAsset(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		parent.ChildAssetIDs = withoutID(parent.ChildAssetIDs, assetID)
+		err = s.putAsset(ctx, parent)
+		if err != nil {
+			return err
+		}
+	}
+	if asset.InstalledIn != "" {
+		err = deleteIndexEntry(ctx, installedInIndex, asset.InstalledIn, assetID)
+		if err != nil {
+			return err
+		}
+	}
+	err = updateStageIndex(ctx, assetID, asset.CurrentLifecycleStage, "")
+	if err != nil {
+		return err
+	}
+	err = updateTagIndex(ctx, assetID, asset.Tags, nil)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(assetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete asset %s: %w", assetID, err)
+	}
+	return nil
+}
+
+// deleteIndexEntry removes the entry of the objectType index under attributes.
+func deleteIndexEntry(ctx contractapi.TransactionContextInterface, objectType string, attributes ...string) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create %s index key: %w", objectType, err)
+	}
+	err = ctx.GetStub().DelState(indexKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s index entry: %w", objectType, err)
+	}
+	return nil
+}
+
+// deleteEventIndexes removes the index entries written while recording the
+// asset's events: its creation, client event IDs, suppliers, machines,
+// material batches, agents and inspection results. Client event IDs are looked
+// up in their index, so those of archived events go too.
+func deleteEventIndexes(ctx contractapi.TransactionContextInterface, assetID string, events []ProvenanceEvent) error {
+	if chain := custodyChain(events); len(chain) > 0 {
+		err := deleteIndexEntry(ctx, createdIndex, chain[0].StartTime, assetID)
+		if err != nil {
+			return err
+		}
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(clientEventIndex, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to query client event index: %w", err)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to iterate client event index: %w", err)
+		}
+		err = ctx.GetStub().DelState(entry.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete client event index entry: %w", err)
+		}
+	}
+
+	for _, batchID := range materialBatchesOf(events) {
+		err = deleteIndexEntry(ctx, materialBatchIndex, batchID, assetID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, event := range events {
+		if event.EventType == EventMaterialCertification && event.SupplierID != "" {
+			err = deleteIndexEntry(ctx, supplierIndex, event.SupplierID, assetID)
+			if err != nil {
+				return err
+			}
+		}
+		if event.MachineID != "" {
+			err = deleteIndexEntry(ctx, machineIndex, event.MachineID, assetID)
+			if err != nil {
+				return err
+			}
+		}
+		if event.AgentID != "" {
+			err = deleteIndexEntry(ctx, agentIndex, event.AgentID, event.Timestamp, event.TxID, assetID)
+			if err != nil {
+				return err
+			}
+		}
+		if event.PrimaryInspectionResult != "" {
+			err = deleteIndexEntry(ctx, inspectionResultIndex, event.PrimaryInspectionResult, event.Timestamp, assetID, event.TxID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AssetDiff is the result of GetAssetDiff. TxIDs lists the transactions of the
+// asset's history after FromTxID up to and including ToTxID, and Changes the
+// asset fields whose value differs between the two versions, by field name.
+type AssetDiff struct {
+	AssetID       string        `json:"assetID"`
+	FromTxID      string        `json:"fromTxID"`
+	FromTimestamp string        `json:"fromTimestamp"`
+	ToTxID        string        `json:"toTxID"`
+	ToTimestamp   string        `json:"toTimestamp"`
+	TxIDs         []string      `json:"txIDs"`
+	Changes       []FieldChange `json:"changes"`
+}
+
+// GetAssetDiff returns the field-level difference between the asset as
+// written by fromTxID and as written by toTxID, both read from the ledger
+// history. Both txIDs must belong to the asset's history with fromTxID the
+// earlier. The history list itself is not diffed; the transactions in between
+// are listed in TxIDs instead. Requires the peer history database.
+func (s *SmartContract) GetAssetDiff(ctx contractapi.TransactionContextInterface, assetID string, fromTxID string, toTxID string) (*AssetDiff, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	from, to := -1, -1
+	for i, txID := range asset.HistoryTxIDs {
+		switch txID {
+		case fromTxID:
+			from = i
+		case toTxID:
+			to = i
+		}
+	}
+	if from == -1 {
+		return nil, fmt.Errorf("%w: transaction %s is not part of the history of asset %s", ErrInvalidArgument, fromTxID, assetID)
+	}
+	if to == -1 {
+		return nil, fmt.Errorf("%w: transaction %s is not part of the history of asset %s", ErrInvalidArgument, toTxID, assetID)
+	}
+	if from >= to {
+		return nil, fmt.Errorf("%w: fromTxID %s must precede toTxID %s in the history of asset %s", ErrInvalidArgument, fromTxID, toTxID, assetID)
+	}
+
+	versions, err := s.GetAssetStateHistory(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	var fromVersion, toVersion *AssetStateVersion
+	for i := range versions {
+		switch versions[i].TxID {
+		case fromTxID:
+			fromVersion = &versions[i]
+		case toTxID:
+			toVersion = &versions[i]
+		}
+	}
+	if fromVersion == nil || fromVersion.Asset == nil {
+		return nil, fmt.Errorf("%w: the ledger history holds no version of asset %s written by transaction %s", ErrNotFound, assetID, fromTxID)
+	}
+	if toVersion == nil || toVersion.Asset == nil {
+		return nil, fmt.Errorf("%w: the ledger history holds no version of asset %s written by transaction %s", ErrNotFound, assetID, toTxID)
+	}
+
+	before, err := jsonFields(fromVersion.Asset)
+	if err != nil {
+		return nil, err
+	}
+	after, err := jsonFields(toVersion.Asset)
+	if err != nil {
+		return nil, err
+	}
+	fields := []string{}
+	for field := range before {
+		fields = append(fields, field)
+	}
+	for field := range after {
+		if _, ok := before[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	diff := &AssetDiff{
+		AssetID:       assetID,
+		FromTxID:      fromTxID,
+		FromTimestamp: fromVersion.Timestamp,
+		ToTxID:        toTxID,
+		ToTimestamp:   toVersion.Timestamp,
+		TxIDs:         append([]string{}, asset.HistoryTxIDs[from+1:to+1]...),
+		Changes:       []FieldChange{},
+	}
+	for _, field := range fields {
+		if field == "historyTxIDs" || string(before[field]) == string(after[field]) {
+			continue
+		}
+		diff.Changes = append(diff.Changes, FieldChange{Field: field, Before: before[field], After: after[field]})
+	}
+	return diff, nil
+}
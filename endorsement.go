@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// endorsementPolicy builds a signature policy requiring a peer of every given
+// MSP to endorse.
+func endorsementPolicy(mspIDs []string) ([]byte, error) {
+	envelope := &common.SignaturePolicyEnvelope{
+		Rule: &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_NOutOf_{
+				NOutOf: &common.SignaturePolicy_NOutOf{N: int32(len(mspIDs))},
+			},
+		},
+	}
+	rules := envelope.Rule.GetNOutOf()
+	for i, mspID := range mspIDs {
+		role, err := proto.Marshal(&msp.MSPRole{MspIdentifier: mspID, Role: msp.MSPRole_PEER})
+		if err != nil {
+			return nil, err
+		}
+		envelope.Identities = append(envelope.Identities, &msp.MSPPrincipal{
+			PrincipalClassification: msp.MSPPrincipal_ROLE,
+			Principal:               role,
+		})
+		rules.Rules = append(rules.Rules, &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_SignedBy{SignedBy: int32(i)},
+		})
+	}
+	return proto.Marshal(envelope)
+}
+
+// policyMSPIDs returns the MSPs named by the role principals of a signature policy.
+func policyMSPIDs(policy []byte) ([]string, error) {
+	var envelope common.SignaturePolicyEnvelope
+	err := proto.Unmarshal(policy, &envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode endorsement policy: %w", err)
+	}
+	mspIDs := []string{}
+	for _, identity := range envelope.Identities {
+		if identity.PrincipalClassification != msp.MSPPrincipal_ROLE {
+			continue
+		}
+		var role msp.MSPRole
+		err = proto.Unmarshal(identity.Principal, &role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode endorsement policy principal: %w", err)
+		}
+		mspIDs = append(mspIDs, role.MspIdentifier)
+	}
+	return mspIDs, nil
+}
+
+// SetAssetEndorsementPolicy attaches a state-based endorsement policy to the
+// asset, so that any later update needs endorsements from peers of every
+// listed MSP. An empty list removes the policy and falls back to the chaincode
+// endorsement policy. Only the owner or the admin MSP may set it.
+func (s *SmartContract) SetAssetEndorsementPolicy(ctx contractapi.TransactionContextInterface, assetID string, mspIDs []string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, mspID := range mspIDs {
+		if mspID == "" {
+			return fmt.Errorf("%w: mspIDs must not contain empty values", ErrInvalidArgument)
+		}
+		if !seen[mspID] {
+			seen[mspID] = true
+			unique = append(unique, mspID)
+		}
+	}
+	sort.Strings(unique)
+
+	var policy []byte
+	if len(unique) > 0 {
+		policy, err = endorsementPolicy(unique)
+		if err != nil {
+			return err
+		}
+	}
+	payload, err := json.Marshal(unique)
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventEndorsementPolicySet,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: string(payload),
+	}
+	err = s.recordAssetEvent(ctx, asset, event, "")
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetStateValidationParameter(assetID, policy)
+	if err != nil {
+		return fmt.Errorf("failed to set endorsement policy: %w", err)
+	}
+	return nil
+}
+
+// GetAssetEndorsementPolicy returns the MSPs whose peers must all endorse
+// updates of the asset, or an empty list when it has no state-based policy.
+func (s *SmartContract) GetAssetEndorsementPolicy(ctx contractapi.TransactionContextInterface, assetID string) ([]string, error) {
+	_, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ctx.GetStub().GetStateValidationParameter(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endorsement policy: %w", err)
+	}
+	if len(policy) == 0 {
+		return []string{}, nil
+	}
+	return policyMSPIDs(policy)
+}
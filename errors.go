@@ -0,0 +1,22 @@
+package main
+
+import "errors"
+
+// Sentinel errors classify contract failures. Their text is a stable
+// machine-readable code: errors are returned as "<CODE>: <message>", so SDK
+// clients can match the code prefix, while chaincode callers use errors.Is.
+var (
+	// ErrAssetNotFound reports that the requested asset does not exist.
+	ErrAssetNotFound = errors.New("ASSET_NOT_FOUND")
+	// ErrAssetExists reports that an asset with the given ID already exists.
+	ErrAssetExists = errors.New("ASSET_EXISTS")
+	// ErrNotFound reports that a record other than an asset does not exist.
+	ErrNotFound = errors.New("NOT_FOUND")
+	// ErrUnauthorized reports that the caller may not perform the operation.
+	ErrUnauthorized = errors.New("UNAUTHORIZED")
+	// ErrInvalidArgument reports a missing or malformed argument.
+	ErrInvalidArgument = errors.New("INVALID_ARGUMENT")
+	// ErrInvalidState reports that the operation conflicts with the current
+	// state of the ledger, such as a disallowed lifecycle transition.
+	ErrInvalidState = errors.New("INVALID_STATE")
+)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// deleteEventRecord deletes the event a transaction recorded for an asset,
+// under its composite key and its legacy keys.
+func deleteEventRecord(ctx contractapi.TransactionContextInterface, txID string, assetID string) error {
+	key, err := eventKey(ctx, txID, assetID)
+	if err != nil {
+		return err
+	}
+	for _, k := range append([]string{key}, legacyEventKeys(txID, assetID)...) {
+		err = ctx.GetStub().DelState(k)
+		if err != nil {
+			return fmt.Errorf("failed to delete event %s: %w", txID, err)
+		}
+	}
+	return nil
+}
+
+// MigrateEventKeys rekeys the events of an asset's history still stored under
+// a legacy EVENT_ key to the composite event key namespaced by the asset, and
+// returns the number of events rekeyed. Events already rekeyed are left alone,
+// so the migration can be rerun. An EVENT_<txID> key predating per-asset keys
+// is moved to the first asset migrated whose history names the transaction;
+// the other assets of such a transaction had already lost their own record.
+// Only the owner or the admin MSP may migrate an asset.
+func (s *SmartContract) MigrateEventKeys(ctx contractapi.TransactionContextInterface, assetID string) (int, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	for _, txID := range asset.HistoryTxIDs {
+		key, err := eventKey(ctx, txID, assetID)
+		if err != nil {
+			return 0, err
+		}
+		eventJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read from world state: %w", err)
+		}
+		if eventJSON != nil {
+			continue
+		}
+		for _, legacyKey := range legacyEventKeys(txID, assetID) {
+			eventJSON, err = ctx.GetStub().GetState(legacyKey)
+			if err != nil {
+				return 0, fmt.Errorf("failed to read from world state: %w", err)
+			}
+			if eventJSON == nil {
+				continue
+			}
+			err = ctx.GetStub().PutState(key, eventJSON)
+			if err != nil {
+				return 0, fmt.Errorf("failed to put event state: %w", err)
+			}
+			err = ctx.GetStub().DelState(legacyKey)
+			if err != nil {
+				return 0, fmt.Errorf("failed to delete event %s: %w", txID, err)
+			}
+			migrated++
+			break
+		}
+	}
+	return migrated, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RecertificationPayload is the on-chain payload of a MATERIAL_RECERTIFIED event.
+type RecertificationPayload struct {
+	PreviousExpiry string `json:"previousExpiry"`
+}
+
+// parseExpiry validates an optional RFC3339 expiry timestamp and returns it
+// normalized to UTC, or an empty string when none is given.
+func parseExpiry(name string, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	expiry, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s must be an RFC3339 timestamp: %v", ErrInvalidArgument, name, err)
+	}
+	return expiry.UTC().Format(time.RFC3339), nil
+}
+
+// requireUnexpiredMaterial returns an error when the material's certification
+// has expired by the transaction timestamp. A material without an expiry never
+// expires.
+func requireUnexpiredMaterial(ctx contractapi.TransactionContextInterface, material *Asset) error {
+	if material.ExpiryTimestamp == "" {
+		return nil
+	}
+	expiry, err := time.Parse(time.RFC3339, material.ExpiryTimestamp)
+	if err != nil {
+		return fmt.Errorf("material %s has an invalid expiry %q: %w", material.AssetID, material.ExpiryTimestamp, err)
+	}
+	now, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %w", err)
+	}
+	if !now.AsTime().Before(expiry) {
+		return fmt.Errorf("%w: the certification of material %s expired at %s and must be renewed with RecertifyMaterial", ErrInvalidState, material.AssetID, material.ExpiryTimestamp)
+	}
+	return nil
+}
+
+// RecertifyMaterial extends the certification of a material batch to
+// newExpiry, an RFC3339 timestamp later than both the current expiry and the
+// transaction time, and records a MATERIAL_RECERTIFIED event committing the
+// off-chain evidence of the re-certification. Only the owner may recertify.
+// It returns the txID that recorded the re-certification, or the one that
+// already recorded clientEventID for the asset.
+func (s *SmartContract) RecertifyMaterial(ctx contractapi.TransactionContextInterface, assetID string, newExpiry string, offChainDataHash string, clientEventID string) (string, error) {
+	if newExpiry == "" {
+		return "", fmt.Errorf("%w: newExpiry must not be empty", ErrInvalidArgument)
+	}
+	expiry, err := parseExpiry("newExpiry", newExpiry)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwner(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	if asset.CurrentLifecycleStage != StageMaterialCertified {
+		return "", fmt.Errorf("%w: the asset %s is in stage %s, not a certified material", ErrInvalidState, assetID, asset.CurrentLifecycleStage)
+	}
+	extended, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return "", err
+	}
+	now, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %w", err)
+	}
+	if !extended.After(now.AsTime()) {
+		return "", fmt.Errorf("%w: newExpiry %s is not in the future", ErrInvalidArgument, newExpiry)
+	}
+	if asset.ExpiryTimestamp != "" {
+		current, err := time.Parse(time.RFC3339, asset.ExpiryTimestamp)
+		if err == nil && !extended.After(current) {
+			return "", fmt.Errorf("%w: newExpiry %s does not extend the current expiry %s", ErrInvalidArgument, newExpiry, asset.ExpiryTimestamp)
+		}
+	}
+	payload, err := json.Marshal(RecertificationPayload{PreviousExpiry: asset.ExpiryTimestamp})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventMaterialRecertified,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payload),
+		ExpiryTimestamp:    expiry,
+	}
+	asset.ExpiryTimestamp = expiry
+	return s.recordClientEvent(ctx, asset, event, "")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// failureIndex is the composite key object type linking a material batch to
+// the field failures of parts made from it.
+const failureIndex = "failure~batch~assetID~txID"
+
+// ServiceFailure summarizes an in-service failure report.
+type ServiceFailure struct {
+	AssetID      string `json:"assetID"`
+	TxID         string `json:"txID"`
+	Timestamp    string `json:"timestamp"`
+	FailureMode  string `json:"failureMode"`
+	ServiceHours int    `json:"serviceHours"`
+}
+
+// materialBatchesOf returns the distinct material batches referenced by the events.
+func materialBatchesOf(events []ProvenanceEvent) []string {
+	seen := make(map[string]bool)
+	var batches []string
+	for _, event := range events {
+		for _, batchID := range []string{event.MaterialBatchID, event.MaterialUsedID} {
+			if batchID != "" && !seen[batchID] {
+				seen[batchID] = true
+				batches = append(batches, batchID)
+			}
+		}
+	}
+	return batches
+}
+
+// RecordServiceFailure records an in-service failure of a part and indexes it
+// against every material batch the part's history references.
+func (s *SmartContract) RecordServiceFailure(ctx contractapi.TransactionContextInterface, assetID string, failureMode string, serviceHours int, offChainDataHash string, clientEventID string) (string, error) {
+	if failureMode == "" {
+		return "", fmt.Errorf("%w: failureMode must not be empty", ErrInvalidArgument)
+	}
+	if serviceHours < 0 {
+		return "", fmt.Errorf("%w: serviceHours must not be negative, got %d", ErrInvalidArgument, serviceHours)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	failure := ServiceFailure{
+		AssetID:      assetID,
+		TxID:         ctx.GetStub().GetTxID(),
+		Timestamp:    timestamp,
+		FailureMode:  failureMode,
+		ServiceHours: serviceHours,
+	}
+	failureJSON, err := json.Marshal(failure)
+	if err != nil {
+		return "", err
+	}
+	for _, batchID := range materialBatchesOf(assetEvents(ctx, asset)) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(failureIndex, []string{batchID, assetID, failure.TxID})
+		if err != nil {
+			return "", fmt.Errorf("failed to create failure index key: %w", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, failureJSON)
+		if err != nil {
+			return "", fmt.Errorf("failed to put failure index: %w", err)
+		}
+	}
+	event := ProvenanceEvent{
+		EventType:        EventServiceFailure,
+		AgentID:          clientMSPID,
+		ClientEventID:    clientEventID,
+		OffChainDataHash: offChainDataHash,
+		FailureMode:      failureMode,
+		ServiceHours:     serviceHours,
+	}
+	asset.Failed = true
+	return s.recordClientEvent(ctx, asset, event, "")
+}
+
+// GetFailuresByMaterialBatch returns the field failures of parts whose history
+// references the given material batch.
+func (s *SmartContract) GetFailuresByMaterialBatch(ctx contractapi.TransactionContextInterface, batchID string) ([]ServiceFailure, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(failureIndex, []string{batchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failure index: %w", err)
+	}
+	defer iterator.Close()
+
+	failures := []ServiceFailure{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate failure index: %w", err)
+		}
+		var failure ServiceFailure
+		err = json.Unmarshal(entry.Value, &failure)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
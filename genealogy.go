@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GenealogyLinkPayload is the on-chain payload of a GENEALOGY_LINK event.
+type GenealogyLinkPayload struct {
+	ParentAssetID string `json:"parentAssetID"`
+	ChildAssetID  string `json:"childAssetID"`
+}
+
+// AssetLineage is an asset together with the lineage of each of its parents.
+type AssetLineage struct {
+	Asset   *Asset          `json:"asset"`
+	Parents []*AssetLineage `json:"parents"`
+}
+
+// withoutID returns ids with every occurrence of id removed.
+func withoutID(ids []string, id string) []string {
+	kept := []string{}
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+// containsID reports whether ids contains id.
+func containsID(ids []string, id string) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestorIDs returns the IDs of every asset the given asset descends from,
+// each listed once, nearest ancestors first.
+func (s *SmartContract) ancestorIDs(ctx contractapi.TransactionContextInterface, asset *Asset) ([]string, error) {
+	seen := map[string]bool{asset.AssetID: true}
+	var ancestors []string
+	queue := append([]string{}, asset.ParentAssetIDs...)
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		if seen[parentID] {
+			continue
+		}
+		seen[parentID] = true
+		ancestors = append(ancestors, parentID)
+		parent, err := s.ReadAsset(ctx, parentID)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, parent.ParentAssetIDs...)
+	}
+	return ancestors, nil
+}
+
+// LinkAssets records that childID was made from parentID, such as a part
+// printed from a material batch or an assembly built from a sub-part. Only the
+// owner of the child may link it. Links that would create a cycle are rejected.
+// It returns the txID that recorded the link, or the one that already recorded
+// clientEventID for the child.
+func (s *SmartContract) LinkAssets(ctx contractapi.TransactionContextInterface, parentID string, childID string, clientEventID string) (string, error) {
+	if parentID == childID {
+		return "", fmt.Errorf("%w: an asset cannot be linked to itself", ErrInvalidArgument)
+	}
+	parent, err := s.ReadAsset(ctx, parentID)
+	if err != nil {
+		return "", err
+	}
+	child, err := s.ReadAsset(ctx, childID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwner(ctx, child)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, childID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	for _, id := range child.ParentAssetIDs {
+		if id == parentID {
+			return "", fmt.Errorf("%w: the asset %s is already linked to parent %s", ErrInvalidState, childID, parentID)
+		}
+	}
+	ancestors, err := s.ancestorIDs(ctx, parent)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ancestors {
+		if id == childID {
+			return "", fmt.Errorf("%w: linking %s under %s would create a cycle", ErrInvalidState, childID, parentID)
+		}
+	}
+
+	payload, err := json.Marshal(GenealogyLinkPayload{ParentAssetID: parentID, ChildAssetID: childID})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventGenealogyLink,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+	}
+	parent.ChildAssetIDs = append(parent.ChildAssetIDs, childID)
+	err = s.appendAssetEvent(ctx, parent, event, "")
+	if err != nil {
+		return "", err
+	}
+	child.ParentAssetIDs = append(child.ParentAssetIDs, parentID)
+	err = s.appendAssetEvent(ctx, child, event, "")
+	if err != nil {
+		return "", err
+	}
+	err = emitChaincodeEvent(ctx, event.EventType, parentID, childID)
+	if err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// GetAssetLineage returns the ancestry tree of an asset, walking its parents
+// recursively up to the source material batches.
+func (s *SmartContract) GetAssetLineage(ctx contractapi.TransactionContextInterface, assetID string) (*AssetLineage, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	lineage := &AssetLineage{Asset: asset, Parents: []*AssetLineage{}}
+	for _, parentID := range asset.ParentAssetIDs {
+		parentLineage, err := s.GetAssetLineage(ctx, parentID)
+		if err != nil {
+			return nil, err
+		}
+		lineage.Parents = append(lineage.Parents, parentLineage)
+	}
+	return lineage, nil
+}
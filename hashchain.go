@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// eventHash returns the hex SHA-256 digest of the canonical JSON encoding of
+// the event, the ProvenanceEvent with its TxID set encoded by canonicalJSON.
+// The hash does not depend on the typed encoding of the event.
+func eventHash(event ProvenanceEvent) (string, error) {
+	eventJSON, err := canonicalJSON(plainEvent(event))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event JSON: %w", err)
+	}
+	digest := sha256.Sum256(eventJSON)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// legacyEventHash returns the event hash used before canonicalJSON: the hex
+// SHA-256 digest of the event as marshalled by encoding/json with every field
+// as declared.
+func legacyEventHash(event ProvenanceEvent) (string, error) {
+	eventJSON, err := json.Marshal(plainEvent(event))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event JSON: %w", err)
+	}
+	digest := sha256.Sum256(eventJSON)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// chainedEventHash returns the hash of predecessor as stored in the
+// PreviousEventHash of an event of schemaVersion: events recorded before
+// schema version 2 chain to the legacy hash.
+func chainedEventHash(predecessor ProvenanceEvent, schemaVersion int) (string, error) {
+	if schemaVersion < canonicalHashSchemaVersion {
+		return legacyEventHash(predecessor)
+	}
+	return eventHash(predecessor)
+}
+
+// previousEventHash returns the hash of the asset's latest event, which the
+// next event stores to extend the asset's hash chain. It is empty for an asset
+// without history or whose latest event record is missing, which
+// VerifyHistoryChain reports as a broken chain.
+func previousEventHash(ctx contractapi.TransactionContextInterface, asset *Asset) (string, error) {
+	if len(asset.HistoryTxIDs) == 0 {
+		return "", nil
+	}
+	txID := asset.HistoryTxIDs[len(asset.HistoryTxIDs)-1]
+	eventJSON, err := readEventJSON(ctx, txID, asset.AssetID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if eventJSON == nil {
+		return "", nil
+	}
+	var event ProvenanceEvent
+	err = json.Unmarshal(eventJSON, &event)
+	if err != nil {
+		return "", err
+	}
+	event.TxID = txID
+	return eventHash(event)
+}
+
+// ChainVerification is the result of walking an asset's event hash chain.
+// When Valid is false, BrokenAtTxID is the first event whose link does not
+// match and Reason says why.
+type ChainVerification struct {
+	Valid        bool   `json:"valid"`
+	CheckedLinks int    `json:"checkedLinks"`
+	BrokenAtTxID string `json:"brokenAtTxID"`
+	Reason       string `json:"reason"`
+}
+
+// VerifyHistoryChain walks the asset's events in history order and checks that
+// each one stores the hash of the event before it, proving no event was
+// reordered, altered or dropped. Events recorded before the chain was
+// introduced carry no hash and are not checked, but once an event carries
+// one, every later event must too. After ArchiveHistory, the first remaining
+// event chains to the last archived event recorded in the archive summary.
+func (s *SmartContract) VerifyHistoryChain(ctx contractapi.TransactionContextInterface, assetID string) (*ChainVerification, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	events, skipped := loadAssetEvents(ctx, asset)
+	if len(skipped) > 0 {
+		return &ChainVerification{
+			BrokenAtTxID: skipped[0],
+			Reason:       "the event cannot be read",
+		}, nil
+	}
+
+	verification := &ChainVerification{Valid: true}
+	chained := false
+	if len(events) > 0 && events[0].PreviousEventHash != "" {
+		archivedTail := false
+		for _, summary := range archiveSummaries(events) {
+			archivedTail = archivedTail || summary.LastArchivedHash == events[0].PreviousEventHash
+		}
+		if !archivedTail {
+			return &ChainVerification{
+				BrokenAtTxID: events[0].TxID,
+				Reason:       "the first event chains to an event that is neither in the history nor archived",
+			}, nil
+		}
+		chained = true
+		verification.CheckedLinks++
+	}
+	for i := 1; i < len(events); i++ {
+		event := events[i]
+		if event.PreviousEventHash == "" {
+			if chained {
+				return &ChainVerification{
+					CheckedLinks: verification.CheckedLinks,
+					BrokenAtTxID: event.TxID,
+					Reason:       "the event does not carry the hash of its predecessor",
+				}, nil
+			}
+			continue
+		}
+		chained = true
+		expected, err := chainedEventHash(events[i-1], event.SchemaVersion)
+		if err != nil {
+			return nil, err
+		}
+		if event.PreviousEventHash != expected {
+			return &ChainVerification{
+				CheckedLinks: verification.CheckedLinks,
+				BrokenAtTxID: event.TxID,
+				Reason:       fmt.Sprintf("the stored hash %s does not match predecessor %s, which hashes to %s", event.PreviousEventHash, events[i-1].TxID, expected),
+			}, nil
+		}
+		verification.CheckedLinks++
+	}
+	return verification, nil
+}
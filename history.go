@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AssetStateVersion is one version of an asset as recorded by the ledger.
+// Asset is omitted for the version that deleted the asset.
+type AssetStateVersion struct {
+	TxID      string `json:"txID"`
+	Timestamp string `json:"timestamp"`
+	IsDelete  bool   `json:"isDelete"`
+	Asset     *Asset `json:"asset,omitempty" metadata:",optional"`
+}
+
+// GetAssetStateHistory returns every version of the asset record straight from
+// the ledger history, in the order the peer returns them (newest first on
+// Fabric 2.x). Requires the peer history database.
+func (s *SmartContract) GetAssetStateHistory(ctx contractapi.TransactionContextInterface, assetID string) ([]AssetStateVersion, error) {
+	err := validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetHistoryForKey(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset history: %w", err)
+	}
+	defer iterator.Close()
+
+	versions := []AssetStateVersion{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate asset history: %w", err)
+		}
+		version := AssetStateVersion{
+			TxID:     modification.TxId,
+			IsDelete: modification.IsDelete,
+		}
+		if modification.Timestamp != nil {
+			version.Timestamp = modification.Timestamp.AsTime().UTC().Format(time.RFC3339)
+		}
+		if !modification.IsDelete {
+			var asset Asset
+			err = json.Unmarshal(modification.Value, &asset)
+			if err != nil {
+				return nil, err
+			}
+			version.Asset = &asset
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: the asset %s does not exist", ErrAssetNotFound, assetID)
+	}
+	return versions, nil
+}
+
+// parseTimeBound parses an optional RFC3339 time range bound.
+func parseTimeBound(name string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	bound, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC3339 timestamp, got %q: %v", ErrInvalidArgument, name, value, err)
+	}
+	return &bound, nil
+}
+
+// QueryAssetHistory returns the asset's events of the given type (all types
+// when empty) recorded between startTime and endTime inclusive, in
+// chronological order. Both bounds are optional RFC3339 timestamps.
+func (s *SmartContract) QueryAssetHistory(ctx contractapi.TransactionContextInterface, assetID string, eventType string, startTime string, endTime string) (*HistoryResult, error) {
+	start, err := parseTimeBound("startTime", startTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeBound("endTime", endTime)
+	if err != nil {
+		return nil, err
+	}
+	if start != nil && end != nil && start.After(*end) {
+		return nil, fmt.Errorf("%w: startTime %s is after endTime %s", ErrInvalidArgument, startTime, endTime)
+	}
+	var typ EventType
+	if eventType != "" {
+		typ, err = parseEventType(ctx, eventType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, skipped := loadAssetEvents(ctx, asset)
+	events := []ProvenanceEvent{}
+	for _, event := range loaded {
+		if typ != "" && event.EventType != typ {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, event.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("event %s has an invalid timestamp %q: %w", event.TxID, event.Timestamp, err)
+		}
+		if (start != nil && timestamp.Before(*start)) || (end != nil && timestamp.After(*end)) {
+			continue
+		}
+		events = append(events, event)
+	}
+	sortEvents(events)
+	return &HistoryResult{Events: events, Skipped: skipped}, nil
+}
+
+// GetEvent returns the event a transaction recorded for an asset. Events are
+// keyed by asset, so a transaction that recorded events for several assets
+// returns the one of the given asset. The event stays readable after the asset
+// is deleted.
+func (s *SmartContract) GetEvent(ctx contractapi.TransactionContextInterface, assetID string, txID string) (*ProvenanceEvent, error) {
+	err := validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	if txID == "" {
+		return nil, fmt.Errorf("%w: txID must not be empty", ErrInvalidArgument)
+	}
+	eventJSON, err := readEventJSON(ctx, txID, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if eventJSON == nil {
+		return nil, fmt.Errorf("%w: the event %s of asset %s does not exist", ErrNotFound, txID, assetID)
+	}
+	var event ProvenanceEvent
+	err = json.Unmarshal(eventJSON, &event)
+	if err != nil {
+		return nil, err
+	}
+	event.TxID = txID
+	return &event, nil
+}
+
+// MultiAssetHistory holds the histories of several assets by asset ID, and the
+// requested IDs of assets that do not exist.
+type MultiAssetHistory struct {
+	Histories map[string]*HistoryResult `json:"histories"`
+	NotFound  []string                  `json:"notFound"`
+}
+
+// GetMultiAssetHistory returns the provenance histories of several assets in a
+// single call. Missing assets are listed in NotFound instead of failing the call.
+func (s *SmartContract) GetMultiAssetHistory(ctx contractapi.TransactionContextInterface, assetIDs []string) (*MultiAssetHistory, error) {
+	result := &MultiAssetHistory{
+		Histories: make(map[string]*HistoryResult),
+		NotFound:  []string{},
+	}
+	for _, assetID := range assetIDs {
+		if _, done := result.Histories[assetID]; done {
+			continue
+		}
+		history, err := s.GetAssetHistory(ctx, assetID)
+		if errors.Is(err, ErrAssetNotFound) {
+			result.NotFound = append(result.NotFound, assetID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Histories[assetID] = history
+	}
+	return result, nil
+}
+
+// AssetSnapshot is an asset together with its latest event. LatestEventMissing
+// is set, and LatestEvent left nil, when the asset has history but the record
+// of its last transaction cannot be loaded.
+type AssetSnapshot struct {
+	Asset              *Asset           `json:"asset"`
+	LatestEvent        *ProvenanceEvent `json:"latestEvent,omitempty" metadata:",optional"`
+	LatestEventMissing bool             `json:"latestEventMissing"`
+}
+
+// GetAssetSnapshot returns the asset and its latest event, resolved from the
+// last txID of its history, in a single call.
+func (s *SmartContract) GetAssetSnapshot(ctx contractapi.TransactionContextInterface, assetID string) (*AssetSnapshot, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &AssetSnapshot{Asset: asset}
+	if len(asset.HistoryTxIDs) == 0 {
+		return snapshot, nil
+	}
+	txID := asset.HistoryTxIDs[len(asset.HistoryTxIDs)-1]
+	eventJSON, err := readEventJSON(ctx, txID, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	var event ProvenanceEvent
+	if eventJSON == nil || json.Unmarshal(eventJSON, &event) != nil {
+		snapshot.LatestEventMissing = true
+		return snapshot, nil
+	}
+	event.TxID = txID
+	snapshot.LatestEvent = &event
+	return snapshot, nil
+}
+
+// HistoryPage is a window of an asset's chronologically ordered events. Total
+// is the number of events in the whole history.
+type HistoryPage struct {
+	Events  []ProvenanceEvent `json:"events"`
+	Total   int               `json:"total"`
+	Skipped []string          `json:"skipped"`
+}
+
+// GetAssetHistoryPaginated returns up to limit events of the asset's history,
+// ordered as GetAssetHistory, starting at offset. A limit of 0 returns every
+// event from offset on, and an offset past the end returns no events.
+func (s *SmartContract) GetAssetHistoryPaginated(ctx contractapi.TransactionContextInterface, assetID string, offset int, limit int) (*HistoryPage, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative, got %d", ErrInvalidArgument, offset)
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative, got %d", ErrInvalidArgument, limit)
+	}
+	history, err := s.GetAssetHistory(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	total := len(history.Events)
+	start := offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if limit > 0 && start+limit < total {
+		end = start + limit
+	}
+	return &HistoryPage{
+		Events:  history.Events[start:end],
+		Total:   total,
+		Skipped: history.Skipped,
+	}, nil
+}
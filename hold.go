@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// holdValidator rejects every event against a held asset except placing and
+// releasing the hold itself, recalling the asset and deleting it.
+type holdValidator struct{}
+
+func (holdValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if !pending.Asset.Held {
+		return nil
+	}
+	switch pending.Event.EventType {
+	case EventHoldPlaced, EventHoldReleased, EventRecalled, EventDelete:
+		return nil
+	}
+	return fmt.Errorf("%w: the asset %s is on hold: %s", ErrInvalidState, pending.Asset.AssetID, pending.Asset.HoldReason)
+}
+
+// PlaceHold quarantines an asset while a suspected quality issue is
+// investigated. No other event may be recorded against it until the hold is
+// released. Only the owner or the admin MSP may place a hold.
+func (s *SmartContract) PlaceHold(ctx contractapi.TransactionContextInterface, assetID string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("%w: a hold reason is required", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return err
+	}
+	if asset.Held {
+		return fmt.Errorf("%w: the asset %s is already on hold", ErrInvalidState, assetID)
+	}
+	event := ProvenanceEvent{
+		EventType:          EventHoldPlaced,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: reason,
+	}
+	asset.Held = true
+	asset.HoldReason = reason
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
+
+// ReleaseHold lifts the hold on an asset. Only the owner or the admin MSP may
+// release a hold.
+func (s *SmartContract) ReleaseHold(ctx contractapi.TransactionContextInterface, assetID string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return err
+	}
+	if !asset.Held {
+		return fmt.Errorf("%w: the asset %s is not on hold", ErrInvalidState, assetID)
+	}
+	event := ProvenanceEvent{
+		EventType:          EventHoldReleased,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: asset.HoldReason,
+	}
+	asset.Held = false
+	asset.HoldReason = ""
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// clientEventIndex is the composite key object type mapping a client-supplied
+// event ID to the transaction that recorded it for an asset. The Record*
+// events, AddHistoryEvent, AddSignedEvent, AmendEvent, SetAssetTags,
+// PatchAsset, RecertifyMaterial, TransferCustody, IssueCertificate,
+// CloseNonConformance and SupersedeOffChainData take an optional
+// clientEventID; when a client retries one after a timeout with the same ID,
+// the original txID is returned and nothing new is recorded. The transactions
+// touching several assets key the ID on one of them: BulkTransition on its
+// first asset, SplitAsset on the parent, LinkAssets on the child and
+// InitiateRecall on the root, while UpdateTaggedAssetsMetadata treats the call
+// as a retry when any tagged asset already recorded the ID. The transactions
+// without one refuse a retry on their own: the material certifications create
+// an asset that then already exists, RecordNonConformance refuses a known NCR
+// ID, RecordAssembly and RecordDisassembly refuse components that are already
+// (or no longer) linked, and transfers, holds, certification proposals and
+// approvals, revocations, archives and anchors check the state the first
+// attempt left behind. SetMaterialQuantity and SetAssetEndorsementPolicy set
+// an absolute value, so a retry records the same value again.
+const clientEventIndex = "clientEvent~assetID~clientEventID"
+
+// priorClientEvent returns the txID that already recorded clientEventID for
+// the asset, or an empty string when the event is new or no ID was supplied.
+func priorClientEvent(ctx contractapi.TransactionContextInterface, assetID string, clientEventID string) (string, error) {
+	if clientEventID == "" {
+		return "", nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(clientEventIndex, []string{assetID, clientEventID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create client event index key: %w", err)
+	}
+	txID, err := ctx.GetStub().GetState(indexKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read client event index: %w", err)
+	}
+	return string(txID), nil
+}
+
+// indexClientEvent remembers that txID recorded the event's client event ID
+// for the asset. Events without a client event ID are not indexed.
+func indexClientEvent(ctx contractapi.TransactionContextInterface, assetID string, event ProvenanceEvent, txID string) error {
+	if event.ClientEventID == "" {
+		return nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(clientEventIndex, []string{assetID, event.ClientEventID})
+	if err != nil {
+		return fmt.Errorf("failed to create client event index key: %w", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte(txID))
+	if err != nil {
+		return fmt.Errorf("failed to put client event index: %w", err)
+	}
+	return nil
+}
+
+// recordClientEvent records the event like recordAssetEvent and returns the
+// txID that recorded it.
+func (s *SmartContract) recordClientEvent(ctx contractapi.TransactionContextInterface, asset *Asset, event ProvenanceEvent, nextStage LifecycleStage) (string, error) {
+	err := s.recordAssetEvent(ctx, asset, event, nextStage)
+	if err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestSetAssetTagsRetry(t *testing.T) {
+	s := new(SmartContract)
+	l := seedLedger(t)
+	var first string
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) (err error) {
+		first, err = s.SetAssetTags(ctx, "PART-1", `{"program":"next"}`, "client-1")
+		return err
+	})
+	ctx, stub := l.context("", testSupplierMSP, nil)
+	retried, err := s.SetAssetTags(ctx, "PART-1", `{"program":"next"}`, "client-1")
+	if err != nil || retried != first {
+		t.Fatalf("retried SetAssetTags() = %q, %v, want %q", retried, err, first)
+	}
+	if len(stub.writes) != 0 || len(stub.events) != 0 {
+		t.Fatalf("retried SetAssetTags wrote %v and emitted %v", stub.writes, stub.events)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// installedInIndex is the composite key object type linking a parent product
+// serial number to the parts installed in it.
+const installedInIndex = "installedIn~assetID"
+
+// RecordInstallation records that a certified part has been installed into a
+// larger product. Only the owner of the part may install it, and a part whose
+// certificate was revoked or that was recalled cannot be installed.
+func (s *SmartContract) RecordInstallation(ctx contractapi.TransactionContextInterface, assetID string, parentSerialNumber string, position string, offChainDataHash string, clientEventID string) (string, error) {
+	if parentSerialNumber == "" {
+		return "", fmt.Errorf("%w: parentSerialNumber must not be empty", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwner(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	if asset.InstalledIn != "" {
+		return "", fmt.Errorf("%w: the asset %s is already installed in %s", ErrInvalidState, assetID, asset.InstalledIn)
+	}
+	if asset.CurrentLifecycleStage != StageCertified {
+		return "", fmt.Errorf("%w: the asset %s is in stage %s, not CERTIFIED", ErrInvalidState, assetID, asset.CurrentLifecycleStage)
+	}
+	if asset.CertificateRevoked {
+		return "", fmt.Errorf("%w: the certificate %s of asset %s has been revoked", ErrInvalidState, asset.CertificateID, assetID)
+	}
+	if asset.Recalled {
+		return "", fmt.Errorf("%w: the asset %s has been recalled", ErrInvalidState, assetID)
+	}
+	event := ProvenanceEvent{
+		EventType:            EventInstalled,
+		AgentID:              clientMSPID,
+		ClientEventID:        clientEventID,
+		OffChainDataHash:     offChainDataHash,
+		ParentSerialNumber:   parentSerialNumber,
+		InstallationPosition: position,
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(installedInIndex, []string{parentSerialNumber, assetID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation index key: %w", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return "", fmt.Errorf("failed to put installation index: %w", err)
+	}
+	asset.InstalledIn = parentSerialNumber
+	return s.recordClientEvent(ctx, asset, event, StageInstalled)
+}
+
+// GetPartsInstalledIn returns all parts installed in the given parent product.
+func (s *SmartContract) GetPartsInstalledIn(ctx contractapi.TransactionContextInterface, parentSerialNumber string) ([]*Asset, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(installedInIndex, []string{parentSerialNumber})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query installation index: %w", err)
+	}
+	defer iterator.Close()
+
+	assets := []*Asset{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate installation index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split installation index key: %w", err)
+		}
+		asset, err := s.ReadAsset(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
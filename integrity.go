@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// IntegrityIssue is one discrepancy found by CheckIntegrity. Check names the
+// check that failed and TxID the event concerned, if any.
+type IntegrityIssue struct {
+	Check  string `json:"check"`
+	TxID   string `json:"txID"`
+	Detail string `json:"detail"`
+}
+
+// IntegrityReport is the result of CheckIntegrity. Consistent is true when no
+// issue was found.
+type IntegrityReport struct {
+	AssetID       string             `json:"assetID"`
+	Consistent    bool               `json:"consistent"`
+	CheckedEvents int                `json:"checkedEvents"`
+	Chain         *ChainVerification `json:"chain"`
+	Issues        []IntegrityIssue   `json:"issues"`
+}
+
+// compositeKeyExists reports whether the composite key built from objectType
+// and attributes is present in the world state.
+func compositeKeyExists(ctx contractapi.TransactionContextInterface, objectType string, attributes ...string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return false, fmt.Errorf("failed to create %s key: %w", objectType, err)
+	}
+	value, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	return value != nil, nil
+}
+
+// CheckIntegrity checks the consistency of the asset's records: every txID of
+// its history resolves to an event record stored for that transaction, the
+// hash chain is intact, the stage, installation, tag, certificate, client
+// event, supplier, material batch, agent and inspection result indexes point
+// back to the asset, and its genealogy links are mirrored by its parents and
+// children.
+// Every discrepancy found is reported rather than failing on the first.
+// Assets recorded before an index was introduced are reported as missing from
+// that index.
+func (s *SmartContract) CheckIntegrity(ctx contractapi.TransactionContextInterface, assetID string) (*IntegrityReport, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	report := &IntegrityReport{AssetID: assetID, Issues: []IntegrityIssue{}}
+	addIssue := func(check string, txID string, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, IntegrityIssue{Check: check, TxID: txID, Detail: fmt.Sprintf(format, args...)})
+	}
+	// requireKey reports an issue when the index entry is missing.
+	requireKey := func(check string, txID string, objectType string, attributes ...string) error {
+		exists, err := compositeKeyExists(ctx, objectType, attributes...)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			addIssue(check, txID, "the %s index has no entry %v", objectType, attributes)
+		}
+		return nil
+	}
+
+	events := []ProvenanceEvent{}
+	for _, txID := range asset.HistoryTxIDs {
+		eventJSON, err := readEventJSON(ctx, txID, assetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %w", err)
+		}
+		if eventJSON == nil {
+			addIssue("event", txID, "no event record exists for the transaction")
+			continue
+		}
+		var event ProvenanceEvent
+		if err := json.Unmarshal(eventJSON, &event); err != nil {
+			addIssue("event", txID, "the event record cannot be decoded: %v", err)
+			continue
+		}
+		if event.TxID != "" && event.TxID != txID {
+			addIssue("event", txID, "the event record names transaction %s", event.TxID)
+		}
+		event.TxID = txID
+		events = append(events, event)
+	}
+	report.CheckedEvents = len(events)
+
+	report.Chain, err = s.VerifyHistoryChain(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if !report.Chain.Valid {
+		addIssue("hashChain", report.Chain.BrokenAtTxID, "%s", report.Chain.Reason)
+	}
+
+	if asset.CurrentLifecycleStage != "" {
+		if err := requireKey("stageIndex", "", stageIndex, string(asset.CurrentLifecycleStage), assetID); err != nil {
+			return nil, err
+		}
+	}
+	if asset.InstalledIn != "" {
+		if err := requireKey("installedInIndex", "", installedInIndex, asset.InstalledIn, assetID); err != nil {
+			return nil, err
+		}
+	}
+	tagKeys := make([]string, 0, len(asset.Tags))
+	for key := range asset.Tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		if err := requireKey("tagIndex", "", tagIndex, key, asset.Tags[key], assetID); err != nil {
+			return nil, err
+		}
+	}
+	if asset.CertificateID != "" {
+		record, err := readCertificate(ctx, asset.CertificateID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case record == nil:
+			addIssue("certificate", "", "no record exists for certificate %s", asset.CertificateID)
+		case record.AssetID != assetID:
+			addIssue("certificate", record.IssueTxID, "certificate %s is recorded for asset %s", asset.CertificateID, record.AssetID)
+		}
+	}
+	for _, event := range events {
+		if event.ClientEventID != "" {
+			txID, err := priorClientEvent(ctx, assetID, event.ClientEventID)
+			if err != nil {
+				return nil, err
+			}
+			if txID != event.TxID {
+				addIssue("clientEventIndex", event.TxID, "client event %s is indexed to transaction %q", event.ClientEventID, txID)
+			}
+		}
+		if event.EventType == EventMaterialCertification && event.SupplierID != "" {
+			if err := requireKey("supplierIndex", event.TxID, supplierIndex, event.SupplierID, assetID); err != nil {
+				return nil, err
+			}
+		}
+		if event.MachineID != "" {
+			if err := requireKey("machineIndex", event.TxID, machineIndex, event.MachineID, assetID); err != nil {
+				return nil, err
+			}
+		}
+		for _, batchID := range materialBatchesOf([]ProvenanceEvent{event}) {
+			if err := requireKey("materialBatchIndex", event.TxID, materialBatchIndex, batchID, assetID); err != nil {
+				return nil, err
+			}
+		}
+		if event.AgentID != "" {
+			if err := requireKey("agentIndex", event.TxID, agentIndex, event.AgentID, event.Timestamp, event.TxID, assetID); err != nil {
+				return nil, err
+			}
+		}
+		if event.PrimaryInspectionResult != "" {
+			if err := requireKey("inspectionResultIndex", event.TxID, inspectionResultIndex, event.PrimaryInspectionResult, event.Timestamp, assetID, event.TxID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, parentID := range asset.ParentAssetIDs {
+		parent, err := s.ReadAsset(ctx, parentID)
+		if err != nil {
+			addIssue("genealogy", "", "parent %s cannot be read: %v", parentID, err)
+			continue
+		}
+		if !containsID(parent.ChildAssetIDs, assetID) {
+			addIssue("genealogy", "", "parent %s does not list the asset as a child", parentID)
+		}
+	}
+	for _, childID := range asset.ChildAssetIDs {
+		child, err := s.ReadAsset(ctx, childID)
+		if err != nil {
+			addIssue("genealogy", "", "child %s cannot be read: %v", childID, err)
+			continue
+		}
+		if !containsID(child.ParentAssetIDs, assetID) {
+			addIssue("genealogy", "", "child %s does not list the asset as a parent", childID)
+		}
+	}
+
+	report.Consistent = len(report.Issues) == 0
+	return report, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AssetBundle is an asset together with every event of its history, the
+// document exchanged with external PLM systems.
+type AssetBundle struct {
+	Asset  *Asset            `json:"asset"`
+	Events []ProvenanceEvent `json:"events"`
+}
+
+// ExportAsset returns the asset and all its events, in history order, as a
+// single bundle that ImportAsset accepts unchanged.
+func (s *SmartContract) ExportAsset(ctx contractapi.TransactionContextInterface, assetID string) (*AssetBundle, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	events, skipped := loadAssetEvents(ctx, asset)
+	if len(skipped) > 0 {
+		return nil, fmt.Errorf("%w: the events of transactions %v of asset %s cannot be read", ErrInvalidState, skipped, assetID)
+	}
+	return &AssetBundle{Asset: asset, Events: events}, nil
+}
+
+// ImportAsset stores an asset bundle produced by ExportAsset or an external
+// system. Every txID of the asset's history must either be supplied in the
+// bundle or already resolve to a stored event of the asset, and every supplied
+// event must belong to the history. The asset and events are stored as given,
+// without running the event validators, so a round trip is lossless, but
+// every event is indexed as recording it would have been, and a certificate
+// it carries must not belong to another asset. The schema version of the
+// asset and of each event must be one this contract knows. An existing asset
+// is only replaced when overwrite is set. Only the admin MSP may import
+// assets.
+func (s *SmartContract) ImportAsset(ctx contractapi.TransactionContextInterface, assetJSON string, overwrite bool) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	var bundle AssetBundle
+	err = json.Unmarshal([]byte(assetJSON), &bundle)
+	if err != nil {
+		return fmt.Errorf("%w: assetJSON must be an asset bundle: %v", ErrInvalidArgument, err)
+	}
+	asset := bundle.Asset
+	if asset == nil {
+		return fmt.Errorf("%w: the bundle has no asset", ErrInvalidArgument)
+	}
+	err = validateAssetID(asset.AssetID)
+	if err != nil {
+		return err
+	}
+	if asset.Owner == "" {
+		return fmt.Errorf("%w: the asset %s has no owner", ErrInvalidArgument, asset.AssetID)
+	}
+	if asset.SchemaVersion < 0 || asset.SchemaVersion > currentSchemaVersion {
+		return fmt.Errorf("%w: the asset %s has schema version %d, outside 0 to %d", ErrInvalidArgument, asset.AssetID, asset.SchemaVersion, currentSchemaVersion)
+	}
+
+	inHistory := make(map[string]bool)
+	for _, txID := range asset.HistoryTxIDs {
+		inHistory[txID] = true
+	}
+	supplied := make(map[string]ProvenanceEvent)
+	for i, event := range bundle.Events {
+		if event.TxID == "" || !inHistory[event.TxID] {
+			return fmt.Errorf("%w: event %d has txID %q, which is not in the history of asset %s", ErrInvalidArgument, i, event.TxID, asset.AssetID)
+		}
+		if _, ok := supplied[event.TxID]; ok {
+			return fmt.Errorf("%w: the event of transaction %s is supplied more than once", ErrInvalidArgument, event.TxID)
+		}
+		if event.SchemaVersion < 0 || event.SchemaVersion > currentSchemaVersion {
+			return fmt.Errorf("%w: the event of transaction %s has schema version %d, outside 0 to %d", ErrInvalidArgument, event.TxID, event.SchemaVersion, currentSchemaVersion)
+		}
+		err = uniqueCertificateValidator{}.Validate(ctx, &PendingEvent{Asset: asset, Event: &event})
+		if err != nil {
+			return err
+		}
+		supplied[event.TxID] = event
+	}
+	// history holds every event of the asset in history order, supplied or
+	// already stored, to be indexed.
+	var history []ProvenanceEvent
+	for _, txID := range asset.HistoryTxIDs {
+		if event, ok := supplied[txID]; ok {
+			history = append(history, event)
+			continue
+		}
+		eventJSON, err := readEventJSON(ctx, txID, asset.AssetID)
+		if err != nil {
+			return fmt.Errorf("failed to read from world state: %w", err)
+		}
+		if eventJSON == nil {
+			return fmt.Errorf("%w: the history txID %s of asset %s is neither supplied nor stored", ErrInvalidArgument, txID, asset.AssetID)
+		}
+		var event ProvenanceEvent
+		err = json.Unmarshal(eventJSON, &event)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal event %s: %w", txID, err)
+		}
+		if event.TxID == "" {
+			event.TxID = txID
+		}
+		history = append(history, event)
+	}
+
+	err = validateTags(asset.Tags)
+	if err != nil {
+		return err
+	}
+	var previousStage LifecycleStage
+	var previousTags map[string]string
+	existing, err := s.ReadAsset(ctx, asset.AssetID)
+	switch {
+	case err == nil && !overwrite:
+		return fmt.Errorf("%w: the asset %s already exists", ErrAssetExists, asset.AssetID)
+	case err == nil:
+		previousStage = existing.CurrentLifecycleStage
+		previousTags = existing.Tags
+		err = deleteEventIndexes(ctx, existing.AssetID, assetEvents(ctx, existing))
+		if err != nil {
+			return err
+		}
+		if existing.InstalledIn != "" {
+			err = deleteIndexEntry(ctx, installedInIndex, existing.InstalledIn, existing.AssetID)
+			if err != nil {
+				return err
+			}
+		}
+	case !errors.Is(err, ErrAssetNotFound):
+		return err
+	}
+
+	for _, event := range bundle.Events {
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event JSON: %w", err)
+		}
+		key, err := eventKey(ctx, event.TxID, asset.AssetID)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(key, eventJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put event state: %w", err)
+		}
+		err = sequenceEvent(ctx, asset.AssetID, eventJSON)
+		if err != nil {
+			return err
+		}
+		err = indexAgent(ctx, asset.AssetID, event)
+		if err != nil {
+			return err
+		}
+	}
+	if len(history) > 0 {
+		err = putCreationIndex(ctx, asset.AssetID, history[0].Timestamp)
+		if err != nil {
+			return err
+		}
+	}
+	certificateID, certificateRevoked := asset.CertificateID, asset.CertificateRevoked
+	asset.CertificateID = ""
+	for _, event := range history {
+		err = s.indexEvent(ctx, asset, event)
+		if err != nil {
+			return err
+		}
+	}
+	asset.CertificateID, asset.CertificateRevoked = certificateID, certificateRevoked
+	if certificateRevoked && certificateID != "" {
+		err = markImportedRevocation(ctx, certificateID)
+		if err != nil {
+			return err
+		}
+	}
+	if asset.InstalledIn != "" {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(installedInIndex, []string{asset.InstalledIn, asset.AssetID})
+		if err != nil {
+			return fmt.Errorf("failed to create installation index key: %w", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put installation index: %w", err)
+		}
+	}
+	if previousStage != asset.CurrentLifecycleStage {
+		err = updateStageIndex(ctx, asset.AssetID, previousStage, asset.CurrentLifecycleStage)
+		if err != nil {
+			return err
+		}
+	}
+	err = updateTagIndex(ctx, asset.AssetID, previousTags, asset.Tags)
+	if err != nil {
+		return err
+	}
+	err = s.putAsset(ctx, asset)
+	if err != nil {
+		return err
+	}
+	return emitChaincodeEvent(ctx, EventAssetImported, asset.AssetID)
+}
+
+// markImportedRevocation revokes the registry record of a certificate an
+// imported asset holds as revoked, unless it is already revoked.
+func markImportedRevocation(ctx contractapi.TransactionContextInterface, certificateID string) error {
+	record, err := readCertificate(ctx, certificateID)
+	if err != nil || record == nil || record.Revoked {
+		return err
+	}
+	revokedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	record.Revoked = true
+	record.RevocationCode = RevocationOther
+	record.RevocationReason = "revoked before the asset was imported"
+	record.RevokedAt = revokedAt
+	return putCertificate(ctx, record)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// exportedPart returns the bundle ExportAsset produces for the seeded part.
+func exportedPart(t *testing.T) *AssetBundle {
+	t.Helper()
+	s := new(SmartContract)
+	var bundle *AssetBundle
+	seedLedger(t).submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) (err error) {
+		bundle, err = s.ExportAsset(ctx, "PART-1")
+		return err
+	})
+	return bundle
+}
+
+func TestImportAssetIndexesEvents(t *testing.T) {
+	s := new(SmartContract)
+	bundleJSON, err := json.Marshal(exportedPart(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newFakeLedger()
+	l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return s.InitLedger(ctx, testAdminMSP)
+	})
+	l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return s.ImportAsset(ctx, string(bundleJSON), false)
+	})
+
+	ctx, _ := l.context("", testSupplierMSP, nil)
+	asset, err := s.GetAssetByCertificate(ctx, "CERT-1")
+	if err != nil || asset.AssetID != "PART-1" {
+		t.Fatalf("GetAssetByCertificate() = %v, %v, want PART-1", asset, err)
+	}
+	created, err := s.GetAssetsCreatedBetween(ctx, "", "")
+	if err != nil || len(created) != 1 {
+		t.Fatalf("GetAssetsCreatedBetween() = %v, %v, want PART-1", created, err)
+	}
+	agentEvents, err := s.GetEventsByAgent(ctx, testSupplierMSP, "", "")
+	if err != nil || len(agentEvents) != len(asset.HistoryTxIDs) {
+		t.Fatalf("GetEventsByAgent() = %d events, %v, want %d", len(agentEvents), err, len(asset.HistoryTxIDs))
+	}
+	inspections, err := s.GetAssetsByInspectionResult(ctx, "PASS", "", "")
+	if err != nil || len(inspections) != 1 {
+		t.Fatalf("GetAssetsByInspectionResult() = %v, %v, want one inspection", inspections, err)
+	}
+}
+
+func TestImportAssetRejectsBundle(t *testing.T) {
+	s := new(SmartContract)
+	tests := []struct {
+		name    string
+		modify  func(bundle *AssetBundle)
+		wantErr error
+	}{
+		{name: "negative asset schema version", modify: func(b *AssetBundle) { b.Asset.SchemaVersion = -1 }, wantErr: ErrInvalidArgument},
+		{name: "future asset schema version", modify: func(b *AssetBundle) { b.Asset.SchemaVersion = currentSchemaVersion + 1 }, wantErr: ErrInvalidArgument},
+		{name: "future event schema version", modify: func(b *AssetBundle) { b.Events[0].SchemaVersion = currentSchemaVersion + 1 }, wantErr: ErrInvalidArgument},
+		{name: "certificate of another asset", modify: func(b *AssetBundle) { b.Asset.AssetID = "PART-2" }, wantErr: ErrInvalidState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := exportedPart(t)
+			tt.modify(bundle)
+			bundleJSON, err := json.Marshal(bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The seeded ledger already holds CERT-1 for PART-1.
+			l := seedLedger(t)
+			ctx, _ := l.context("", testAdminMSP, nil)
+			err = s.ImportAsset(ctx, string(bundleJSON), true)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ImportAsset() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// stageIndex is the composite key object type listing the assets currently in
+// each lifecycle stage.
+const stageIndex = "stage~assetID"
+
+// updateStageIndex moves an asset's stage index entry from fromStage to
+// toStage and updates the stage counters. An empty stage has no index entry.
+func updateStageIndex(ctx contractapi.TransactionContextInterface, assetID string, fromStage LifecycleStage, toStage LifecycleStage) error {
+	if fromStage != "" {
+		oldKey, err := ctx.GetStub().CreateCompositeKey(stageIndex, []string{string(fromStage), assetID})
+		if err != nil {
+			return fmt.Errorf("failed to create stage index key: %w", err)
+		}
+		err = ctx.GetStub().DelState(oldKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete stage index: %w", err)
+		}
+	}
+	if toStage != "" {
+		newKey, err := ctx.GetStub().CreateCompositeKey(stageIndex, []string{string(toStage), assetID})
+		if err != nil {
+			return fmt.Errorf("failed to create stage index key: %w", err)
+		}
+		err = ctx.GetStub().PutState(newKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put stage index: %w", err)
+		}
+	}
+	return countStageTransition(ctx, assetID, fromStage, toStage)
+}
+
+// GetAssetsByStage returns the assets currently in the given lifecycle stage.
+// It reads the stage index and works on any state database.
+func (s *SmartContract) GetAssetsByStage(ctx contractapi.TransactionContextInterface, stage string) ([]*Asset, error) {
+	lifecycleStage, err := parseLifecycleStage(ctx, stage)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(stageIndex, []string{string(lifecycleStage)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stage index: %w", err)
+	}
+	defer iterator.Close()
+
+	assets := []*Asset{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate stage index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split stage index key: %w", err)
+		}
+		asset, err := s.ReadAsset(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
+
+// transitionConfig names the configuration entries holding transitions
+// registered on top of the default lifecycle.
+const transitionConfig = "transition"
+
+// lifecycleTransitions is the default lifecycle state machine, mapping each
+// stage to the stages an asset may move to next. The empty stage is the
+// starting point of a newly created asset: certified material, or a reprinted
+// part.
+var lifecycleTransitions = map[LifecycleStage][]LifecycleStage{
+	"":                     {StageMaterialCertified, StagePrinted},
+	StageMaterialCertified: {StagePrinted, StageScrapped},
+	StagePrinted:           {StagePostProcessed, StageInspected, StageScrapped},
+	StagePostProcessed:     {StagePostProcessed, StageInspected, StageScrapped},
+	StageInspected:         {StageTested, StageRejected, StageScrapped},
+	StageTested:            {StageCertified, StageCertificateRevoked, StageScrapped},
+	StageCertified:         {StageInstalled, StageCertificateRevoked, StageScrapped},
+	StageInstalled:         {StageDisassembled, StageCertificateRevoked},
+	StageDisassembled:      {StageInstalled, StageInspected, StageCertificateRevoked, StageScrapped},
+}
+
+// allowedTransitions returns the stages reachable from the given stage, both
+// from the default lifecycle and from registered transitions, each listed once.
+func allowedTransitions(ctx contractapi.TransactionContextInterface, fromStage LifecycleStage) ([]LifecycleStage, error) {
+	stages := append([]LifecycleStage{}, lifecycleTransitions[fromStage]...)
+	listed := make(map[LifecycleStage]bool)
+	for _, stage := range stages {
+		listed[stage] = true
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(configObjectType, []string{transitionConfig, string(fromStage)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registered transitions: %w", err)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate registered transitions: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split transition key: %w", err)
+		}
+		if toStage := LifecycleStage(keyParts[2]); !listed[toStage] {
+			listed[toStage] = true
+			stages = append(stages, toStage)
+		}
+	}
+	return stages, nil
+}
+
+// isKnownStage reports whether any transition, default or registered, leads to the stage.
+func isKnownStage(ctx contractapi.TransactionContextInterface, stage LifecycleStage) (bool, error) {
+	for _, targets := range lifecycleTransitions {
+		for _, target := range targets {
+			if target == stage {
+				return true, nil
+			}
+		}
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(configObjectType, []string{transitionConfig})
+	if err != nil {
+		return false, fmt.Errorf("failed to query registered transitions: %w", err)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return false, fmt.Errorf("failed to iterate registered transitions: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return false, fmt.Errorf("failed to split transition key: %w", err)
+		}
+		if LifecycleStage(keyParts[2]) == stage {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lifecycleValidator rejects stage changes the lifecycle state machine does not
+// allow with a TransitionError, and moves to unknown stages.
+type lifecycleValidator struct{}
+
+func (lifecycleValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if pending.NextStage == "" {
+		return nil
+	}
+	currentStage := pending.Asset.CurrentLifecycleStage
+	allowed, err := allowedTransitions(ctx, currentStage)
+	if err != nil {
+		return err
+	}
+	for _, stage := range allowed {
+		if stage == pending.NextStage {
+			return nil
+		}
+	}
+	known, err := isKnownStage(ctx, pending.NextStage)
+	if err != nil {
+		return err
+	}
+	if !known {
+		return fmt.Errorf("%w: unknown lifecycle stage %s", ErrInvalidArgument, pending.NextStage)
+	}
+	definition, err := lifecycleDefinition(ctx)
+	if err != nil {
+		return err
+	}
+	kind := TransitionOutOfOrder
+	switch {
+	case pending.NextStage == currentStage:
+		kind = TransitionRepeated
+	case reachableStages(definition, currentStage)[pending.NextStage]:
+		kind = TransitionSkipped
+	}
+	return &TransitionError{
+		AssetID: pending.Asset.AssetID,
+		From:    currentStage,
+		To:      pending.NextStage,
+		Kind:    kind,
+		Allowed: allowed,
+	}
+}
+
+// Kinds of illegal stage change reported by TransitionError.
+const (
+	// TransitionRepeated is a move to the stage the asset is already in.
+	TransitionRepeated = "REPEATED"
+	// TransitionSkipped is a move to a later stage that skips the stages in
+	// between.
+	TransitionSkipped = "SKIPPED"
+	// TransitionOutOfOrder is a move to a stage the lifecycle never leads to
+	// from the current one.
+	TransitionOutOfOrder = "OUT_OF_ORDER"
+)
+
+// TransitionError reports a stage change the lifecycle state machine does not
+// allow, with the stages the asset may move to instead. It wraps
+// ErrInvalidState, so callers match it with errors.Is or inspect it with
+// errors.As.
+type TransitionError struct {
+	AssetID string
+	From    LifecycleStage
+	To      LifecycleStage
+	Kind    string
+	Allowed []LifecycleStage
+}
+
+func (e *TransitionError) Error() string {
+	allowed := make([]string, 0, len(e.Allowed))
+	for _, stage := range e.Allowed {
+		allowed = append(allowed, string(stage))
+	}
+	sort.Strings(allowed)
+	return fmt.Sprintf("%v: the asset %s cannot move from stage %s to %s (%s); allowed next stages: [%s]", ErrInvalidState, e.AssetID, e.From, e.To, e.Kind, strings.Join(allowed, ", "))
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidState
+}
+
+// lifecycleDefinition returns the whole lifecycle state machine in effect,
+// the default transitions merged with the registered ones.
+func lifecycleDefinition(ctx contractapi.TransactionContextInterface) (map[LifecycleStage][]LifecycleStage, error) {
+	definition := make(map[LifecycleStage][]LifecycleStage, len(lifecycleTransitions))
+	listed := make(map[[2]LifecycleStage]bool)
+	for fromStage, toStages := range lifecycleTransitions {
+		for _, toStage := range toStages {
+			definition[fromStage] = append(definition[fromStage], toStage)
+			listed[[2]LifecycleStage{fromStage, toStage}] = true
+		}
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(configObjectType, []string{transitionConfig})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registered transitions: %w", err)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate registered transitions: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split transition key: %w", err)
+		}
+		transition := [2]LifecycleStage{LifecycleStage(keyParts[1]), LifecycleStage(keyParts[2])}
+		if !listed[transition] {
+			listed[transition] = true
+			definition[transition[0]] = append(definition[transition[0]], transition[1])
+		}
+	}
+	return definition, nil
+}
+
+// reachableStages returns the stages reachable from fromStage through one or
+// more transitions of the lifecycle definition.
+func reachableStages(definition map[LifecycleStage][]LifecycleStage, fromStage LifecycleStage) map[LifecycleStage]bool {
+	reached := make(map[LifecycleStage]bool)
+	queue := append([]LifecycleStage{}, definition[fromStage]...)
+	for len(queue) > 0 {
+		stage := queue[0]
+		queue = queue[1:]
+		if reached[stage] {
+			continue
+		}
+		reached[stage] = true
+		queue = append(queue, definition[stage]...)
+	}
+	return reached
+}
+
+// terminalStageConfig names the configuration entries overriding whether a
+// lifecycle stage is terminal.
+const terminalStageConfig = "terminalStage"
+
+// terminalStages lists the stages after which no event may be recorded against
+// an asset, unless reconfigured.
+var terminalStages = map[LifecycleStage]bool{
+	StageRejected: true,
+	StageScrapped: true,
+}
+
+// isTerminalStage reports whether stage is terminal, honouring any override
+// stored in the ledger configuration.
+func isTerminalStage(ctx contractapi.TransactionContextInterface, stage LifecycleStage) (bool, error) {
+	value, err := getConfig(ctx, terminalStageConfig, string(stage))
+	if err != nil {
+		return false, err
+	}
+	if value == nil {
+		return terminalStages[stage], nil
+	}
+	return strconv.ParseBool(string(value))
+}
+
+// terminalStageValidator rejects any event against an asset in a terminal
+// stage, except the REPRINT linking a rejected part to its replacement, the
+// write-off of material consumed by a part scrapped after its batch and the
+// deletion of the asset.
+type terminalStageValidator struct{}
+
+func (terminalStageValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	switch pending.Event.EventType {
+	case EventReprint, EventMaterialWrittenOff, EventDelete:
+		return nil
+	}
+	terminal, err := isTerminalStage(ctx, pending.Asset.CurrentLifecycleStage)
+	if err != nil {
+		return err
+	}
+	if terminal {
+		return fmt.Errorf("%w: the asset %s is in terminal stage %s and accepts no further events", ErrInvalidState, pending.Asset.AssetID, pending.Asset.CurrentLifecycleStage)
+	}
+	return nil
+}
+
+// SetTerminalStage configures whether stage is terminal. Only the admin MSP may change it.
+func (s *SmartContract) SetTerminalStage(ctx contractapi.TransactionContextInterface, stage string, terminal bool) error {
+	lifecycleStage, err := parseLifecycleStage(ctx, stage)
+	if err != nil {
+		return err
+	}
+	err = requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte(strconv.FormatBool(terminal)), terminalStageConfig, string(lifecycleStage))
+}
+
+// RegisterTransition adds an allowed lifecycle transition on top of the default
+// state machine. fromStage must already be known, while toStage may introduce a
+// new stage. Only the admin MSP may register transitions.
+func (s *SmartContract) RegisterTransition(ctx contractapi.TransactionContextInterface, fromStage string, toStage string) error {
+	if toStage == "" {
+		return fmt.Errorf("%w: toStage must not be empty", ErrInvalidArgument)
+	}
+	from, err := parseLifecycleStage(ctx, fromStage)
+	if err != nil {
+		return err
+	}
+	err = requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte{0x00}, transitionConfig, string(from), toStage)
+}
+
+// GetAllowedTransitions returns the stages an asset in fromStage may move to.
+// An empty fromStage returns the stages a new asset may start in.
+func (s *SmartContract) GetAllowedTransitions(ctx contractapi.TransactionContextInterface, fromStage string) ([]string, error) {
+	var from LifecycleStage
+	if fromStage != "" {
+		var err error
+		from, err = parseLifecycleStage(ctx, fromStage)
+		if err != nil {
+			return nil, err
+		}
+	}
+	allowed, err := allowedTransitions(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	stages := make([]string, 0, len(allowed))
+	for _, stage := range allowed {
+		stages = append(stages, string(stage))
+	}
+	sort.Strings(stages)
+	return stages, nil
+}
+
+// GetLifecycle returns the lifecycle state machine in effect, mapping each
+// stage to the stages an asset may move to next, default and registered
+// transitions alike. The empty stage lists the stages a new asset may start in.
+func (s *SmartContract) GetLifecycle(ctx contractapi.TransactionContextInterface) (map[LifecycleStage][]LifecycleStage, error) {
+	definition, err := lifecycleDefinition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, toStages := range definition {
+		sort.Slice(toStages, func(i, j int) bool { return toStages[i] < toStages[j] })
+	}
+	return definition, nil
+}
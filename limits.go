@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxPayloadSizeConfig names the configuration entry overriding the largest
+// OnChainDataPayload, in bytes, an event may carry.
+const maxPayloadSizeConfig = "maxPayloadSize"
+
+// defaultMaxPayloadSize keeps event payloads well below Fabric's block size limits.
+const defaultMaxPayloadSize = 64 * 1024
+
+// maxPayloadSize returns the configured payload size limit in bytes.
+func maxPayloadSize(ctx contractapi.TransactionContextInterface) (int, error) {
+	value, err := getConfig(ctx, maxPayloadSizeConfig)
+	if err != nil {
+		return 0, err
+	}
+	if value == nil {
+		return defaultMaxPayloadSize, nil
+	}
+	return strconv.Atoi(string(value))
+}
+
+// checkPayloadSize rejects an event whose OnChainDataPayload exceeds the limit.
+func checkPayloadSize(ctx contractapi.TransactionContextInterface, event ProvenanceEvent) error {
+	limit, err := maxPayloadSize(ctx)
+	if err != nil {
+		return err
+	}
+	if len(event.OnChainDataPayload) > limit {
+		return fmt.Errorf("%w: the onChainDataPayload of the %s event is %d bytes, above the limit of %d bytes", ErrInvalidArgument, event.EventType, len(event.OnChainDataPayload), limit)
+	}
+	return nil
+}
+
+// SetMaxPayloadSize sets the largest OnChainDataPayload, in bytes, an event may
+// carry. Only the admin MSP may change it.
+func (s *SmartContract) SetMaxPayloadSize(ctx contractapi.TransactionContextInterface, maxBytes int) error {
+	if maxBytes <= 0 {
+		return fmt.Errorf("%w: maxBytes must be positive, got %d", ErrInvalidArgument, maxBytes)
+	}
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte(strconv.Itoa(maxBytes)), maxPayloadSizeConfig)
+}
+
+// GetMaxPayloadSize returns the largest OnChainDataPayload, in bytes, an event may carry.
+func (s *SmartContract) GetMaxPayloadSize(ctx contractapi.TransactionContextInterface) (int, error) {
+	return maxPayloadSize(ctx)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// machineIndex is the composite key object type linking a machine to the
+// assets whose events it produced, such as the parts it printed.
+const machineIndex = "machine~assetID"
+
+// indexMachine records that the asset has an event naming a machine.
+func indexMachine(ctx contractapi.TransactionContextInterface, assetID string, event ProvenanceEvent) error {
+	if event.MachineID == "" {
+		return nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(machineIndex, []string{event.MachineID, assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create machine index key: %w", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put machine index: %w", err)
+	}
+	return nil
+}
+
+// GetAssetsByMachine returns the IDs of the assets whose events name the
+// machine, such as the parts printed on it. Assets whose events predate the
+// index are not listed.
+func (s *SmartContract) GetAssetsByMachine(ctx contractapi.TransactionContextInterface, machineID string) ([]string, error) {
+	if machineID == "" {
+		return nil, fmt.Errorf("%w: machineID must not be empty", ErrInvalidArgument)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(machineIndex, []string{machineID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query machine index: %w", err)
+	}
+	defer iterator.Close()
+
+	assetIDs := []string{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate machine index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split machine index key: %w", err)
+		}
+		assetIDs = append(assetIDs, keyParts[1])
+	}
+	return assetIDs, nil
+}
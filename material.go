@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// MaterialConsumptionPayload is the on-chain payload of a MATERIAL_CONSUMED event.
+type MaterialConsumptionPayload struct {
+	PrintedAssetID    string  `json:"printedAssetID"`
+	PrintJobID        string  `json:"printJobID"`
+	Amount            float64 `json:"amount"`
+	RemainingQuantity float64 `json:"remainingQuantity"`
+}
+
+// SetMaterialQuantity sets the quantity of a certified material batch that
+// print jobs may consume. Only the owner may set it, and only before any of
+// the material has been consumed.
+func (s *SmartContract) SetMaterialQuantity(ctx contractapi.TransactionContextInterface, assetID string, quantity float64) error {
+	if quantity <= 0 {
+		return fmt.Errorf("%w: quantity must be positive, got %g", ErrInvalidArgument, quantity)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := requireOwner(ctx, asset)
+	if err != nil {
+		return err
+	}
+	if asset.RemainingQuantity != asset.Quantity {
+		return fmt.Errorf("%w: %g of material batch %s has already been consumed", ErrInvalidState, asset.Quantity-asset.RemainingQuantity, assetID)
+	}
+	event := ProvenanceEvent{
+		EventType:          EventMaterialQuantitySet,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: fmt.Sprintf("%g", quantity),
+	}
+	asset.Quantity = quantity
+	asset.RemainingQuantity = quantity
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
+
+// checkMaterialAvailable rejects consuming amount from a material batch that
+// does not track a quantity or has less than amount remaining.
+func checkMaterialAvailable(material *Asset, amount float64) error {
+	if material.Quantity == 0 {
+		return fmt.Errorf("%w: material batch %s does not track a quantity", ErrInvalidState, material.AssetID)
+	}
+	if amount > material.RemainingQuantity {
+		return fmt.Errorf("%w: material batch %s has %g remaining, %g requested", ErrInvalidState, material.AssetID, material.RemainingQuantity, amount)
+	}
+	return nil
+}
+
+// consumeMaterial deducts amount from the remaining quantity of a material
+// batch for a print job and records a MATERIAL_CONSUMED event against the
+// batch. The caller emits the chaincode event of the transaction.
+func (s *SmartContract) consumeMaterial(ctx contractapi.TransactionContextInterface, materialID string, amount float64, printedAssetID string, printJobID string) error {
+	material, err := s.ReadAsset(ctx, materialID)
+	if err != nil {
+		return err
+	}
+	err = checkMaterialAvailable(material, amount)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	material.RemainingQuantity -= amount
+	payload, err := json.Marshal(MaterialConsumptionPayload{
+		PrintedAssetID:    printedAssetID,
+		PrintJobID:        printJobID,
+		Amount:            amount,
+		RemainingQuantity: material.RemainingQuantity,
+	})
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventMaterialConsumed,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: string(payload),
+		PrintJobID:         printJobID,
+	}
+	return s.appendAssetEvent(ctx, material, event, "")
+}
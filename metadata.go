@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// UpdateTaggedAssetsMetadata merges metadataJSON (a JSON object of string
+// values) into the metadata of every asset carrying the given tag, recording a
+// METADATA_UPDATED event on each, and returns the number of assets updated.
+// The caller must own every affected asset or be admin; otherwise nothing is
+// written. Requires the CouchDB state database. Rich query results are not
+// re-validated at commit time, so assets tagged concurrently may be missed.
+// When a tagged asset already recorded clientEventID, the call is a retry of
+// an earlier update: nothing is written, and the number of tagged assets that
+// earlier update reached is returned.
+func (s *SmartContract) UpdateTaggedAssetsMetadata(ctx contractapi.TransactionContextInterface, tag string, metadataJSON string, clientEventID string) (int, error) {
+	if tag == "" || strings.ContainsAny(tag, ".$") {
+		return 0, fmt.Errorf("%w: invalid tag %q: must be non-empty and must not contain '.' or '$'", ErrInvalidArgument, tag)
+	}
+	var metadata map[string]string
+	err := json.Unmarshal([]byte(metadataJSON), &metadata)
+	if err != nil {
+		return 0, fmt.Errorf("%w: metadataJSON must be a JSON object of string values: %v", ErrInvalidArgument, err)
+	}
+	if len(metadata) == 0 {
+		return 0, fmt.Errorf("%w: metadataJSON must contain at least one entry", ErrInvalidArgument)
+	}
+	payload, err := json.Marshal(metadata)
+	if err != nil {
+		return 0, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"tags." + tag: map[string]interface{}{"$exists": true},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	iterator, err := ctx.GetStub().GetQueryResult(string(query))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query tagged assets: %w", err)
+	}
+	defer iterator.Close()
+
+	var assets []*Asset
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to iterate tagged assets: %w", err)
+		}
+		var asset Asset
+		err = json.Unmarshal(result.Value, &asset)
+		if err != nil {
+			return 0, err
+		}
+		if asset.Owner != clientMSPID && !admin {
+			return 0, fmt.Errorf("%w: client from %s does not own tagged asset %s", ErrUnauthorized, clientMSPID, asset.AssetID)
+		}
+		assets = append(assets, &asset)
+	}
+	updated := 0
+	for _, asset := range assets {
+		txID, err := priorClientEvent(ctx, asset.AssetID, clientEventID)
+		if err != nil {
+			return 0, err
+		}
+		if txID != "" {
+			updated++
+		}
+	}
+	if updated > 0 {
+		return updated, nil
+	}
+
+	var assetIDs []string
+	for _, asset := range assets {
+		if asset.Metadata == nil {
+			asset.Metadata = make(map[string]string)
+		}
+		for key, value := range metadata {
+			asset.Metadata[key] = value
+		}
+		event := ProvenanceEvent{
+			EventType:          EventMetadataUpdated,
+			AgentID:            clientMSPID,
+			ClientEventID:      clientEventID,
+			OnChainDataPayload: string(payload),
+		}
+		err = s.appendAssetEvent(ctx, asset, event, "")
+		if err != nil {
+			return 0, err
+		}
+		assetIDs = append(assetIDs, asset.AssetID)
+	}
+	if len(assetIDs) > 0 {
+		err = emitChaincodeEvent(ctx, EventMetadataUpdated, assetIDs...)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(assets), nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// NonConformancePayload is the on-chain payload of the NCR_OPENED and
+// NCR_CLOSED events. Resolution is only set when the report is closed.
+type NonConformancePayload struct {
+	NCRID       string `json:"ncrID"`
+	Description string `json:"description,omitempty"`
+	Resolution  string `json:"resolution,omitempty"`
+}
+
+// RecordNonConformance opens a non-conformance report against an asset for a
+// defect that does not fail it outright. The asset cannot be certified while
+// any of its reports is open.
+func (s *SmartContract) RecordNonConformance(ctx contractapi.TransactionContextInterface, assetID string, ncrID string, description string, offChainDataHash string) error {
+	if ncrID == "" {
+		return fmt.Errorf("%w: ncrID must not be empty", ErrInvalidArgument)
+	}
+	if description == "" {
+		return fmt.Errorf("%w: a description is required", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	for _, openID := range asset.OpenNCRs {
+		if openID == ncrID {
+			return fmt.Errorf("%w: the non-conformance %s is already open on asset %s", ErrInvalidState, ncrID, assetID)
+		}
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	payload, err := json.Marshal(NonConformancePayload{NCRID: ncrID, Description: description})
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventNCROpened,
+		AgentID:            clientMSPID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payload),
+	}
+	asset.OpenNCRs = append(asset.OpenNCRs, ncrID)
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
+
+// CloseNonConformance closes an open non-conformance report with the
+// corrective action taken. It returns the txID that closed the report, or the
+// one that already recorded clientEventID for the asset.
+func (s *SmartContract) CloseNonConformance(ctx contractapi.TransactionContextInterface, assetID string, ncrID string, resolution string, clientEventID string) (string, error) {
+	if resolution == "" {
+		return "", fmt.Errorf("%w: a resolution is required", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	open := false
+	for _, openID := range asset.OpenNCRs {
+		if openID == ncrID {
+			open = true
+			break
+		}
+	}
+	if !open {
+		return "", fmt.Errorf("%w: the non-conformance %s is not open on asset %s", ErrNotFound, ncrID, assetID)
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	payload, err := json.Marshal(NonConformancePayload{NCRID: ncrID, Resolution: resolution})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventNCRClosed,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+	}
+	asset.OpenNCRs = withoutID(asset.OpenNCRs, ncrID)
+	return s.recordClientEvent(ctx, asset, event, "")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// chaincodeEventPrefix prefixes the event type in the name of the chaincode
+// event a transaction emits, as in "ProvenanceEvent.PRINT_JOB".
+const chaincodeEventPrefix = "ProvenanceEvent."
+
+// ChaincodeEventPayload is the payload of the chaincode event a transaction
+// emits after recording provenance events, so clients can react without polling.
+// AssetID is the asset the event was recorded for, the first of AssetIDs when
+// the transaction touched several.
+type ChaincodeEventPayload struct {
+	EventType EventType `json:"eventType"`
+	AssetID   string    `json:"assetID"`
+	AssetIDs  []string  `json:"assetIDs"`
+	TxID      string    `json:"txID"`
+}
+
+// emitChaincodeEvent sets the chaincode event of the transaction, named
+// "ProvenanceEvent.<eventType>". Fabric keeps only the last event set by a
+// transaction, so it must be called once per transaction, listing every asset
+// the event touched, the asset it was recorded for first.
+func emitChaincodeEvent(ctx contractapi.TransactionContextInterface, eventType EventType, assetIDs ...string) error {
+	payload := ChaincodeEventPayload{
+		EventType: eventType,
+		AssetIDs:  assetIDs,
+		TxID:      ctx.GetStub().GetTxID(),
+	}
+	if len(assetIDs) > 0 {
+		payload.AssetID = assetIDs[0]
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(chaincodeEventPrefix+string(eventType), payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set chaincode event: %w", err)
+	}
+	return nil
+}
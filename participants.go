@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// participantRoles maps event types to the supply chain role of the recording organization.
+var participantRoles = map[EventType]string{
+	EventMaterialCertification: "supplier",
+	EventPrinted:               "printer",
+	EventPrintJob:              "printer",
+	EventInspected:             "inspector",
+	EventInspection:            "inspector",
+	EventTested:                "lab",
+	EventFinalTest:             "lab",
+	EventCertified:             "lab",
+	EventCertificationApproved: "lab",
+}
+
+// defaultParticipantRole is used for organizations recording any other event type.
+const defaultParticipantRole = "participant"
+
+// ParticipantInfo describes an organization and the roles it played for an asset.
+type ParticipantInfo struct {
+	MSPID string   `json:"mspID"`
+	Roles []string `json:"roles"`
+}
+
+// GetSupplyChainParticipants returns every distinct organization that recorded
+// events for the asset or any asset in its genealogy, together with the roles
+// it played, ordered by MSPID.
+func (s *SmartContract) GetSupplyChainParticipants(ctx contractapi.TransactionContextInterface, assetID string) ([]ParticipantInfo, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := s.ancestorIDs(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	events := assetEvents(ctx, asset)
+	for _, ancestorID := range ancestors {
+		ancestor, err := s.ReadAsset(ctx, ancestorID)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, assetEvents(ctx, ancestor)...)
+	}
+	roles := make(map[string]map[string]bool)
+	addRole := func(mspID string, role string) {
+		if mspID == "" {
+			return
+		}
+		if roles[mspID] == nil {
+			roles[mspID] = make(map[string]bool)
+		}
+		roles[mspID][role] = true
+	}
+	for _, event := range events {
+		role, ok := participantRoles[event.EventType]
+		if !ok {
+			role = defaultParticipantRole
+		}
+		addRole(event.AgentID, role)
+		addRole(event.SupplierID, "supplier")
+	}
+
+	participants := []ParticipantInfo{}
+	for mspID, roleSet := range roles {
+		participant := ParticipantInfo{MSPID: mspID, Roles: []string{}}
+		for role := range roleSet {
+			participant.Roles = append(participant.Roles, role)
+		}
+		sort.Strings(participant.Roles)
+		participants = append(participants, participant)
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].MSPID < participants[j].MSPID
+	})
+	return participants, nil
+}
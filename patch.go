@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// FieldChange is the before and after value of one field of a record, such as
+// an asset field changed by a patch.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// PatchPayload is the on-chain payload of a PATCH event.
+type PatchPayload struct {
+	Changes []FieldChange `json:"changes"`
+}
+
+// patchableField describes an asset field PatchAsset may replace: who may
+// change it, how to read its current value and how to set a new one.
+type patchableField struct {
+	authorize func(ctx contractapi.TransactionContextInterface, asset *Asset) error
+	get       func(asset *Asset) interface{}
+	set       func(asset *Asset, value json.RawMessage) error
+}
+
+// ownerOrAdmin authorizes the asset owner and the admin MSP.
+func ownerOrAdmin(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	_, err := requireOwnerOrAdmin(ctx, asset)
+	return err
+}
+
+// patchableFields is the whitelist of fields PatchAsset may change, keyed by
+// their JSON name. Every other asset field is immutable through a patch and
+// only changes through its dedicated transaction.
+var patchableFields = map[string]patchableField{
+	"tags": {
+		authorize: ownerOrAdmin,
+		get:       func(asset *Asset) interface{} { return asset.Tags },
+		set: func(asset *Asset, value json.RawMessage) error {
+			var tags map[string]string
+			if err := json.Unmarshal(value, &tags); err != nil {
+				return err
+			}
+			if err := validateTags(tags); err != nil {
+				return err
+			}
+			asset.Tags = tags
+			return nil
+		},
+	},
+	"metadata": {
+		authorize: ownerOrAdmin,
+		get:       func(asset *Asset) interface{} { return asset.Metadata },
+		set: func(asset *Asset, value json.RawMessage) error {
+			var metadata map[string]string
+			if err := json.Unmarshal(value, &metadata); err != nil {
+				return err
+			}
+			asset.Metadata = metadata
+			return nil
+		},
+	},
+	"holdReason": {
+		authorize: func(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+			if !asset.Held {
+				return fmt.Errorf("%w: the asset %s is not on hold", ErrInvalidState, asset.AssetID)
+			}
+			return requireAdmin(ctx)
+		},
+		get: func(asset *Asset) interface{} { return asset.HoldReason },
+		set: func(asset *Asset, value json.RawMessage) error {
+			return json.Unmarshal(value, &asset.HoldReason)
+		},
+	},
+}
+
+// hasJSONField reports whether name is the JSON name of a field of the record
+// type, such as Asset or ProvenanceEvent.
+func hasJSONField(record interface{}, name string) bool {
+	recordType := reflect.TypeOf(record)
+	for i := 0; i < recordType.NumField(); i++ {
+		tag := strings.Split(recordType.Field(i).Tag.Get("json"), ",")[0]
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchAsset replaces the fields named in patchJSON, a JSON object keyed by
+// asset field name, and records a PATCH event with the before and after value
+// of each changed field. Only whitelisted fields may be patched, each with its
+// own authorization; naming any other field rejects the whole patch. It
+// returns the txID that recorded the patch, or the one that already recorded
+// clientEventID for the asset.
+func (s *SmartContract) PatchAsset(ctx contractapi.TransactionContextInterface, assetID string, patchJSON string, clientEventID string) (string, error) {
+	var patch map[string]json.RawMessage
+	err := json.Unmarshal([]byte(patchJSON), &patch)
+	if err != nil {
+		return "", fmt.Errorf("%w: patchJSON must be a JSON object: %v", ErrInvalidArgument, err)
+	}
+	if len(patch) == 0 {
+		return "", fmt.Errorf("%w: patchJSON must contain at least one field", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+
+	previousTags := asset.Tags
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var changes []FieldChange
+	for _, field := range fields {
+		patchable, ok := patchableFields[field]
+		if !ok {
+			if hasJSONField(Asset{}, field) {
+				return "", fmt.Errorf("%w: the field %s is immutable", ErrInvalidArgument, field)
+			}
+			return "", fmt.Errorf("%w: unknown asset field %s", ErrInvalidArgument, field)
+		}
+		err = patchable.authorize(ctx, asset)
+		if err != nil {
+			return "", err
+		}
+		before, err := json.Marshal(patchable.get(asset))
+		if err != nil {
+			return "", err
+		}
+		err = patchable.set(asset, patch[field])
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid value for field %s: %v", ErrInvalidArgument, field, err)
+		}
+		after, err := json.Marshal(patchable.get(asset))
+		if err != nil {
+			return "", err
+		}
+		if string(before) != string(after) {
+			changes = append(changes, FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+	if len(changes) == 0 {
+		return "", fmt.Errorf("%w: the patch does not change asset %s", ErrInvalidArgument, assetID)
+	}
+
+	err = updateTagIndex(ctx, assetID, previousTags, asset.Tags)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	payload, err := json.Marshal(PatchPayload{Changes: changes})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventPatch,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+	}
+	return s.recordClientEvent(ctx, asset, event, "")
+}
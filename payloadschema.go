@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// payloadSchemaConfig names the configuration entries holding the JSON schema
+// the on-chain payload of each event type must satisfy.
+const payloadSchemaConfig = "payloadSchema"
+
+// checkLocalRefs rejects schemas referencing anything outside themselves.
+// Resolving a remote $ref would make endorsement depend on the network.
+func checkLocalRefs(node interface{}) error {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if ref, ok := child.(string); ok && key == "$ref" && !strings.HasPrefix(ref, "#") {
+				return fmt.Errorf("%w: schema reference %q must be local to the schema", ErrInvalidArgument, ref)
+			}
+			if err := checkLocalRefs(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if err := checkLocalRefs(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compilePayloadSchema parses and compiles a payload JSON schema.
+func compilePayloadSchema(schemaJSON []byte) (*gojsonschema.Schema, error) {
+	var document interface{}
+	err := json.Unmarshal(schemaJSON, &document)
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema is not valid JSON: %v", ErrInvalidArgument, err)
+	}
+	err = checkLocalRefs(document)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(document))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON schema: %v", ErrInvalidArgument, err)
+	}
+	return schema, nil
+}
+
+// payloadSchemaValidator rejects an on-chain payload that does not satisfy the
+// schema registered for its event type. Event types without a registered
+// schema accept any payload.
+type payloadSchemaValidator struct{}
+
+func (payloadSchemaValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	schemaJSON, err := getConfig(ctx, payloadSchemaConfig, string(pending.Event.EventType))
+	if err != nil || schemaJSON == nil {
+		return err
+	}
+	schema, err := compilePayloadSchema(schemaJSON)
+	if err != nil {
+		return err
+	}
+	result, err := schema.Validate(gojsonschema.NewStringLoader(pending.Event.OnChainDataPayload))
+	if err != nil {
+		return fmt.Errorf("%w: the payload of %s events must be JSON: %v", ErrInvalidArgument, pending.Event.EventType, err)
+	}
+	if !result.Valid() {
+		var failures []string
+		for _, failure := range result.Errors() {
+			failures = append(failures, failure.String())
+		}
+		return fmt.Errorf("%w: the payload does not match the %s schema: %s", ErrInvalidArgument, pending.Event.EventType, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// RegisterPayloadSchema registers the JSON schema the on-chain payload of
+// eventType must satisfy. An empty schemaJSON removes the schema. Only the
+// admin MSP may register schemas.
+func (s *SmartContract) RegisterPayloadSchema(ctx contractapi.TransactionContextInterface, eventType string, schemaJSON string) error {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	err = requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if schemaJSON == "" {
+		key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{payloadSchemaConfig, string(typ)})
+		if err != nil {
+			return fmt.Errorf("failed to create config key: %w", err)
+		}
+		return ctx.GetStub().DelState(key)
+	}
+	_, err = compilePayloadSchema([]byte(schemaJSON))
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte(schemaJSON), payloadSchemaConfig, string(typ))
+}
+
+// GetPayloadSchema returns the JSON schema registered for eventType, or an
+// empty string when its payloads are not validated.
+func (s *SmartContract) GetPayloadSchema(ctx contractapi.TransactionContextInterface, eventType string) (string, error) {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return "", err
+	}
+	schemaJSON, err := getConfig(ctx, payloadSchemaConfig, string(typ))
+	if err != nil {
+		return "", err
+	}
+	return string(schemaJSON), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PostProcessingPayload is the on-chain payload of a POST_PROCESSING event.
+// Parameters holds the process parameters as reported, such as the
+// temperature, pressure and hold time of a HIP cycle.
+type PostProcessingPayload struct {
+	ProcessType string          `json:"processType"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+	OperatorID  string          `json:"operatorID"`
+}
+
+// RecordPostProcessing records a POST_PROCESSING step applied to a printed
+// part, such as HIP, heat treatment, support removal or CNC finishing, with
+// its process type, the process parameters of parametersJSON, an optional JSON
+// object, and the operator who ran it. The asset moves to POST_PROCESSED, which
+// lies between PRINTED and INSPECTED; a part may go through several steps in
+// sequence, each recorded as its own event. It returns the txID that recorded
+// the step, or the one that already recorded clientEventID for the asset.
+func (s *SmartContract) RecordPostProcessing(ctx contractapi.TransactionContextInterface, assetID string, processType string, parametersJSON string, operatorID string, offChainDataHash string, clientEventID string) (string, error) {
+	if strings.TrimSpace(processType) == "" {
+		return "", fmt.Errorf("%w: processType must not be empty", ErrInvalidArgument)
+	}
+	if operatorID == "" {
+		return "", fmt.Errorf("%w: operatorID must not be empty", ErrInvalidArgument)
+	}
+	payload := PostProcessingPayload{
+		ProcessType: processType,
+		OperatorID:  operatorID,
+	}
+	if parametersJSON != "" {
+		var parameters map[string]interface{}
+		err := json.Unmarshal([]byte(parametersJSON), &parameters)
+		if err != nil {
+			return "", fmt.Errorf("%w: parametersJSON must be a JSON object: %v", ErrInvalidArgument, err)
+		}
+		payload.Parameters = json.RawMessage(parametersJSON)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventPostProcessing,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payloadJSON),
+	}
+	return s.recordClientEvent(ctx, asset, event, StagePostProcessed)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PrintJobPayload is the on-chain payload of a PRINT_JOB event, holding the
+// digests of the build file and of the build parameters the part was printed
+// with.
+type PrintJobPayload struct {
+	BuildFileHash  string `json:"buildFileHash,omitempty" metadata:",optional"`
+	ParametersHash string `json:"parametersHash,omitempty" metadata:",optional"`
+}
+
+// RecordPrintJob records the build job that printed a part as a
+// PRINT_JOB_COMPLETED event, linking it to the machine and the material batch
+// used, and moves the asset to PRINTED. The
+// optional buildFileHash and parametersHash are digests, in the format of an
+// offChainDataHash, of the build file and the build parameters used. A
+// positive materialAmount is deducted from the remaining quantity of the
+// materialUsedID batch, failing when not enough material remains. A
+// materialUsedID naming a material asset whose certification has expired is
+// rejected.
+func (s *SmartContract) RecordPrintJob(ctx contractapi.TransactionContextInterface, assetID string, printJobID string, machineID string, materialUsedID string, materialAmount float64, buildFileHash string, parametersHash string, offChainDataHash string, clientEventID string) (string, error) {
+	err := checkPrintJobArguments(assetID, printJobID, machineID, materialUsedID, materialAmount, buildFileHash, parametersHash)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	event := ProvenanceEvent{
+		EventType:        EventPrintJob,
+		AgentID:          clientMSPID,
+		ClientEventID:    clientEventID,
+		OffChainDataHash: offChainDataHash,
+		PrintJobID:       printJobID,
+		MachineID:        machineID,
+		MaterialUsedID:   materialUsedID,
+	}
+	if buildFileHash != "" || parametersHash != "" {
+		payload, err := json.Marshal(PrintJobPayload{BuildFileHash: buildFileHash, ParametersHash: parametersHash})
+		if err != nil {
+			return "", err
+		}
+		event.OnChainDataPayload = string(payload)
+	}
+	err = s.checkPrintMaterial(ctx, materialUsedID, materialAmount)
+	if err != nil {
+		return "", err
+	}
+	if materialAmount == 0 {
+		return s.recordClientEvent(ctx, asset, event, StagePrinted)
+	}
+	err = s.consumeMaterial(ctx, materialUsedID, materialAmount, assetID, printJobID)
+	if err != nil {
+		return "", err
+	}
+	err = s.appendAssetEvent(ctx, asset, event, StagePrinted)
+	if err != nil {
+		return "", err
+	}
+	err = emitChaincodeEvent(ctx, EventPrintJob, assetID, materialUsedID)
+	if err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// checkPrintJobArguments rejects print job arguments RecordPrintJob would refuse.
+func checkPrintJobArguments(assetID string, printJobID string, machineID string, materialUsedID string, materialAmount float64, buildFileHash string, parametersHash string) error {
+	if printJobID == "" {
+		return fmt.Errorf("%w: printJobID must not be empty", ErrInvalidArgument)
+	}
+	if machineID == "" {
+		return fmt.Errorf("%w: machineID must not be empty", ErrInvalidArgument)
+	}
+	if materialAmount < 0 {
+		return fmt.Errorf("%w: materialAmount must not be negative, got %g", ErrInvalidArgument, materialAmount)
+	}
+	if materialAmount > 0 && materialUsedID == "" {
+		return fmt.Errorf("%w: materialUsedID is required when materialAmount is set", ErrInvalidArgument)
+	}
+	if materialUsedID == assetID {
+		return fmt.Errorf("%w: a part cannot be printed from itself", ErrInvalidArgument)
+	}
+	err := checkDigest("buildFileHash", buildFileHash)
+	if err != nil {
+		return err
+	}
+	return checkDigest("parametersHash", parametersHash)
+}
+
+// checkDigest rejects a non-empty digest argument not in the format of an
+// offChainDataHash.
+func checkDigest(name string, digest string) error {
+	if digest == "" {
+		return nil
+	}
+	if _, _, err := resolveOffChainHash("", digest); err != nil {
+		return fmt.Errorf("%w: %s must be a hex digest such as sha256:<digest>, got %q", ErrInvalidArgument, name, digest)
+	}
+	return nil
+}
+
+// checkPrintMaterial rejects printing from an expired material asset and, when
+// materialAmount is positive, from a missing material or one without enough
+// quantity remaining. A materialUsedID naming no asset is accepted otherwise,
+// as parts may be printed from material tracked off-chain.
+func (s *SmartContract) checkPrintMaterial(ctx contractapi.TransactionContextInterface, materialUsedID string, materialAmount float64) error {
+	if materialUsedID == "" {
+		return nil
+	}
+	material, err := s.ReadAsset(ctx, materialUsedID)
+	if errors.Is(err, ErrAssetNotFound) && materialAmount == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = requireUnexpiredMaterial(ctx, material)
+	if err != nil {
+		return err
+	}
+	if materialAmount > 0 {
+		return checkMaterialAvailable(material, materialAmount)
+	}
+	return nil
+}
+
+// PrintJobSubEvent is one logical event of a print job, such as a layer
+// completion, recorded through RecordPrintJobEventsBatch. ReportedAt is the
+// time reported by the machine, as the sub-events share one transaction time.
+type PrintJobSubEvent struct {
+	EventType        string `json:"eventType"`
+	PrintJobID       string `json:"printJobID"`
+	MachineID        string `json:"machineID"`
+	ReportedAt       string `json:"reportedAt"`
+	OffChainDataHash string `json:"offChainDataHash,omitempty"`
+	Payload          string `json:"payload,omitempty"`
+}
+
+// RecordPrintJobEventsBatch records eventsJSON, a JSON array of
+// PrintJobSubEvent, as a single PRINT_JOB_EVENTS event whose payload holds the
+// whole array. The sub-events are stored under one event key and add a
+// single txID to the asset's history, trading per-event txIDs, timestamps and
+// chaincode events for far fewer transactions; the array as a whole is bound
+// by the payload size limit. It returns the txID that recorded the batch, or
+// the one that already recorded clientEventID for the asset.
+func (s *SmartContract) RecordPrintJobEventsBatch(ctx contractapi.TransactionContextInterface, assetID string, eventsJSON string, clientEventID string) (string, error) {
+	var subEvents []PrintJobSubEvent
+	err := json.Unmarshal([]byte(eventsJSON), &subEvents)
+	if err != nil {
+		return "", fmt.Errorf("%w: eventsJSON must be a JSON array of print job events: %v", ErrInvalidArgument, err)
+	}
+	if len(subEvents) == 0 {
+		return "", fmt.Errorf("%w: eventsJSON must contain at least one entry", ErrInvalidArgument)
+	}
+	for i, subEvent := range subEvents {
+		if subEvent.EventType == "" || subEvent.PrintJobID == "" {
+			return "", fmt.Errorf("%w: entry %d: eventType and printJobID are required", ErrInvalidArgument, i)
+		}
+		if _, err := time.Parse(time.RFC3339, subEvent.ReportedAt); err != nil {
+			return "", fmt.Errorf("%w: entry %d: reportedAt must be an RFC3339 timestamp, got %q", ErrInvalidArgument, i, subEvent.ReportedAt)
+		}
+		if subEvent.OffChainDataHash != "" {
+			if _, _, err := resolveOffChainHash("", subEvent.OffChainDataHash); err != nil {
+				return "", fmt.Errorf("entry %d: %w", i, err)
+			}
+		}
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	payload, err := json.Marshal(subEvents)
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventPrintJobEvents,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+		PrintJobID:         subEvents[0].PrintJobID,
+		MachineID:          subEvents[0].MachineID,
+	}
+	// The envelope only names the job and machine when every sub-event shares them.
+	for _, subEvent := range subEvents[1:] {
+		if subEvent.PrintJobID != event.PrintJobID {
+			event.PrintJobID = ""
+		}
+		if subEvent.MachineID != event.MachineID {
+			event.MachineID = ""
+		}
+	}
+	return s.recordClientEvent(ctx, asset, event, "")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// privateDetailsTransientKey is the transient map entry carrying private
+// details, so they never appear in the transaction proposal arguments.
+const privateDetailsTransientKey = "privateDetails"
+
+// PrivateDetails are the commercially sensitive details of an asset kept in a
+// private data collection.
+type PrivateDetails struct {
+	AssetID    string `json:"assetID"`
+	SupplierID string `json:"supplierID"`
+	Payload    string `json:"payload"`
+}
+
+// requireClientOrgIsPeerOrg returns an error unless the caller belongs to the
+// organization of the endorsing peer, which is how a collection member is
+// recognized from chaincode.
+func requireClientOrgIsPeerOrg(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	peerMSPID, err := shim.GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get peer MSPID: %w", err)
+	}
+	if clientMSPID != peerMSPID {
+		return fmt.Errorf("%w: client from %s is not authorized to access private data on a peer of %s", ErrUnauthorized, clientMSPID, peerMSPID)
+	}
+	return nil
+}
+
+// CreateMaterialCertificationPrivate creates the initial asset like
+// CreateMaterialCertification, but takes the supplier and payload from the
+// "privateDetails" transient entry and writes them to the given private data
+// collection. The public event only carries the SHA-256 of the private details
+// as its on-chain payload.
+func (s *SmartContract) CreateMaterialCertificationPrivate(ctx contractapi.TransactionContextInterface, collection string, assetID string, materialType string, materialBatchID string, offChainDataHash string) error {
+	if collection == "" {
+		return fmt.Errorf("%w: collection must not be empty", ErrInvalidArgument)
+	}
+	err := validateAssetID(assetID)
+	if err != nil {
+		return err
+	}
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to get transient data: %w", err)
+	}
+	detailsJSON, ok := transient[privateDetailsTransientKey]
+	if !ok {
+		return fmt.Errorf("%w: the %s transient entry is required", ErrInvalidArgument, privateDetailsTransientKey)
+	}
+	var details PrivateDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal private details: %w", err)
+	}
+	details.AssetID = assetID
+	detailsJSON, err = json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	exists, err := s.AssetExists(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: the asset %s already exists", ErrAssetExists, assetID)
+	}
+
+	detailsHash := sha256.Sum256(detailsJSON)
+	event := ProvenanceEvent{
+		EventType:          EventMaterialCertification,
+		AgentID:            clientMSPID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: hex.EncodeToString(detailsHash[:]),
+		MaterialType:       materialType,
+		MaterialBatchID:    materialBatchID,
+	}
+	asset := Asset{
+		AssetID:      assetID,
+		Owner:        clientMSPID,
+		HistoryTxIDs: []string{},
+	}
+	err = s.recordAssetEvent(ctx, &asset, event, StageMaterialCertified)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutPrivateData(collection, assetID, detailsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put private details: %w", err)
+	}
+	return nil
+}
+
+// ReadPrivateDetails returns the private details of an asset from the given
+// collection. The caller must belong to the organization of the peer, which
+// only holds the data when that organization is a member of the collection.
+func (s *SmartContract) ReadPrivateDetails(ctx contractapi.TransactionContextInterface, collection string, assetID string) (*PrivateDetails, error) {
+	err := validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireClientOrgIsPeerOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	detailsJSON, err := ctx.GetStub().GetPrivateData(collection, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private details: %w", err)
+	}
+	if detailsJSON == nil {
+		return nil, fmt.Errorf("%w: no private details for asset %s in collection %s", ErrNotFound, assetID, collection)
+	}
+	var details PrivateDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
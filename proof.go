@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ProvenanceProof is a self-contained bundle for verifying an asset's
+// provenance off-chain. EventHashes[i] is the hash of Events[i] as computed by
+// the hash chain, the canonical JSON hash for events chained to by schema
+// version 2 or later, so a verifier can recompute each link from the events
+// alone.
+type ProvenanceProof struct {
+	Asset         *Asset             `json:"asset"`
+	TxIDs         []string           `json:"txIDs"`
+	Events        []ProvenanceEvent  `json:"events"`
+	EventHashes   []string           `json:"eventHashes"`
+	Chain         *ChainVerification `json:"chain"`
+	CertificateID string             `json:"certificateID"`
+	Certificate   *CertificateRecord `json:"certificate,omitempty"`
+}
+
+// GenerateProvenanceProof returns a ProvenanceProof of the asset as a JSON
+// document: the asset, its events in history order with their txIDs and
+// hashes, the result of walking the hash chain and the certificate the asset
+// resolves to. The document holds no generation time and encoding/json sorts
+// map keys, so the same ledger state always yields the same bytes and the
+// hash of the document is stable. An asset with unreadable events cannot be
+// proven and is rejected.
+func (s *SmartContract) GenerateProvenanceProof(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	events, skipped := loadAssetEvents(ctx, asset)
+	if len(skipped) > 0 {
+		return "", fmt.Errorf("%w: the events %v of asset %s cannot be read", ErrInvalidState, skipped, assetID)
+	}
+	chain, err := s.VerifyHistoryChain(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	proof := ProvenanceProof{
+		Asset:         asset,
+		TxIDs:         append([]string{}, asset.HistoryTxIDs...),
+		Events:        events,
+		EventHashes:   make([]string, 0, len(events)),
+		Chain:         chain,
+		CertificateID: asset.CertificateID,
+	}
+	for i, event := range events {
+		// Each event is hashed as its successor chains to it; the latest as
+		// the next event will.
+		successorVersion := currentSchemaVersion
+		if i+1 < len(events) {
+			successorVersion = events[i+1].SchemaVersion
+		}
+		hash, err := chainedEventHash(event, successorVersion)
+		if err != nil {
+			return "", err
+		}
+		proof.EventHashes = append(proof.EventHashes, hash)
+	}
+	if asset.CertificateID != "" {
+		proof.Certificate, err = readCertificate(ctx, asset.CertificateID)
+		if err != nil {
+			return "", err
+		}
+	}
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return "", err
+	}
+	return string(proofJSON), nil
+}
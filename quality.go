@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// QualityResult is the outcome of an inspection or final test.
+type QualityResult string
+
+// ResultRework marks an inspected part that must be reworked before it can
+// pass; it is not a final test result.
+const (
+	ResultPass   QualityResult = "PASS"
+	ResultFail   QualityResult = "FAIL"
+	ResultRework QualityResult = "REWORK"
+)
+
+// qualityResults maps the accepted spellings of a quality result, in upper
+// case, to the result they stand for.
+var qualityResults = map[string]QualityResult{
+	"PASS":     ResultPass,
+	"PASSED":   ResultPass,
+	"FAIL":     ResultFail,
+	"FAILED":   ResultFail,
+	"REJECTED": ResultFail,
+	"REWORK":   ResultRework,
+}
+
+// parseQualityResult converts the result named name in any accepted spelling.
+func parseQualityResult(name string, value string) (QualityResult, error) {
+	if value == "" {
+		return "", fmt.Errorf("%w: %s must not be empty", ErrInvalidArgument, name)
+	}
+	result, ok := qualityResults[strings.ToUpper(strings.TrimSpace(value))]
+	if !ok {
+		return "", fmt.Errorf("%w: %s must be %s, %s or %s, got %q", ErrInvalidArgument, name, ResultPass, ResultFail, ResultRework, value)
+	}
+	return result, nil
+}
+
+// Measurement is a numeric value measured during an inspection or final test.
+// LowerLimit and UpperLimit, when set, bound the values that pass.
+type Measurement struct {
+	Name       string   `json:"name"`
+	Value      float64  `json:"value"`
+	Unit       string   `json:"unit,omitempty" metadata:",optional"`
+	LowerLimit *float64 `json:"lowerLimit,omitempty" metadata:",optional"`
+	UpperLimit *float64 `json:"upperLimit,omitempty" metadata:",optional"`
+}
+
+// withinLimits reports whether the measured value lies within its limits.
+func (m Measurement) withinLimits() bool {
+	return (m.LowerLimit == nil || m.Value >= *m.LowerLimit) && (m.UpperLimit == nil || m.Value <= *m.UpperLimit)
+}
+
+// QualityPayload is the on-chain payload of an INSPECTION or FINAL_TEST event
+// carrying measurements.
+type QualityPayload struct {
+	Measurements []Measurement `json:"measurements"`
+}
+
+// parseMeasurements parses measurementsJSON, a JSON array of Measurement. An
+// empty string means no measurements were taken.
+func parseMeasurements(measurementsJSON string) ([]Measurement, error) {
+	if measurementsJSON == "" {
+		return nil, nil
+	}
+	var measurements []Measurement
+	err := json.Unmarshal([]byte(measurementsJSON), &measurements)
+	if err != nil {
+		return nil, fmt.Errorf("%w: measurementsJSON must be a JSON array of measurements: %v", ErrInvalidArgument, err)
+	}
+	return measurements, nil
+}
+
+// checkQualityResult parses the result named name, PASS, FAIL or REWORK, and
+// checks the measurements backing it: each has a unique name and consistent
+// limits, and a PASS result has every measurement within its limits.
+func checkQualityResult(name string, value string, measurements []Measurement) (QualityResult, error) {
+	result, err := parseQualityResult(name, value)
+	if err != nil {
+		return "", err
+	}
+	seen := make(map[string]bool)
+	for i, measurement := range measurements {
+		if measurement.Name == "" {
+			return "", fmt.Errorf("%w: measurement %d has no name", ErrInvalidArgument, i)
+		}
+		if seen[measurement.Name] {
+			return "", fmt.Errorf("%w: measurement %s is listed more than once", ErrInvalidArgument, measurement.Name)
+		}
+		seen[measurement.Name] = true
+		if measurement.LowerLimit != nil && measurement.UpperLimit != nil && *measurement.LowerLimit > *measurement.UpperLimit {
+			return "", fmt.Errorf("%w: measurement %s has a lower limit above its upper limit", ErrInvalidArgument, measurement.Name)
+		}
+		if result == ResultPass && !measurement.withinLimits() {
+			return "", fmt.Errorf("%w: measurement %s of %g is outside its limits, so the result cannot be %s", ErrInvalidArgument, measurement.Name, measurement.Value, ResultPass)
+		}
+	}
+	return result, nil
+}
+
+// qualityPayload returns the on-chain payload recording measurements, or an
+// empty string when there are none.
+func qualityPayload(measurements []Measurement) (string, error) {
+	if len(measurements) == 0 {
+		return "", nil
+	}
+	payload, err := json.Marshal(QualityPayload{Measurements: measurements})
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// RecordInspection records the result of the primary inspection of a part,
+// PASS, FAIL or REWORK, and moves the asset to INSPECTED. measurementsJSON is an
+// optional JSON array of Measurement backing the result. Recording an
+// inspection requires the qa role attribute unless reconfigured.
+func (s *SmartContract) RecordInspection(ctx contractapi.TransactionContextInterface, assetID string, primaryInspectionResult string, measurementsJSON string, offChainDataHash string, clientEventID string) (string, error) {
+	measurements, err := parseMeasurements(measurementsJSON)
+	if err != nil {
+		return "", err
+	}
+	result, err := checkQualityResult("primaryInspectionResult", primaryInspectionResult, measurements)
+	if err != nil {
+		return "", err
+	}
+	payload, err := qualityPayload(measurements)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	event := ProvenanceEvent{
+		EventType:               EventInspection,
+		AgentID:                 clientMSPID,
+		ClientEventID:           clientEventID,
+		OffChainDataHash:        offChainDataHash,
+		PrimaryInspectionResult: string(result),
+		OnChainDataPayload:      payload,
+	}
+	return s.recordClientEvent(ctx, asset, event, StageInspected)
+}
+
+// RecordFinalTest records the final test of a part against a test standard,
+// PASS or FAIL. A passing result moves the asset to TESTED, a failing one to
+// REJECTED. measurementsJSON is an optional JSON array of Measurement backing
+// the result. Recording a final test requires the qa role attribute unless
+// reconfigured.
+func (s *SmartContract) RecordFinalTest(ctx contractapi.TransactionContextInterface, assetID string, testStandardApplied string, finalTestResult string, measurementsJSON string, certificateID string, offChainDataHash string, clientEventID string) (string, error) {
+	measurements, err := parseMeasurements(measurementsJSON)
+	if err != nil {
+		return "", err
+	}
+	result, err := checkFinalTestArguments(testStandardApplied, finalTestResult, certificateID, measurements)
+	if err != nil {
+		return "", err
+	}
+	payload, err := qualityPayload(measurements)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	event := ProvenanceEvent{
+		EventType:           EventFinalTest,
+		AgentID:             clientMSPID,
+		ClientEventID:       clientEventID,
+		OffChainDataHash:    offChainDataHash,
+		TestStandardApplied: testStandardApplied,
+		FinalTestResult:     string(result),
+		CertificateID:       certificateID,
+		OnChainDataPayload:  payload,
+	}
+	return s.recordClientEvent(ctx, asset, event, finalTestStage(string(result)))
+}
+
+// checkFinalTestArguments rejects final test arguments RecordFinalTest would
+// refuse and returns the parsed result.
+func checkFinalTestArguments(testStandardApplied string, finalTestResult string, certificateID string, measurements []Measurement) (QualityResult, error) {
+	if testStandardApplied == "" {
+		return "", fmt.Errorf("%w: testStandardApplied must not be empty", ErrInvalidArgument)
+	}
+	result, err := checkQualityResult("finalTestResult", finalTestResult, measurements)
+	if err != nil {
+		return "", err
+	}
+	if result == ResultRework {
+		return "", fmt.Errorf("%w: finalTestResult must be %s or %s, got %q", ErrInvalidArgument, ResultPass, ResultFail, finalTestResult)
+	}
+	if result == ResultFail && certificateID != "" {
+		return "", fmt.Errorf("%w: a certificate cannot be issued for a failing final test", ErrInvalidArgument)
+	}
+	return result, nil
+}
+
+// finalTestStage returns the stage a final test with the given result moves
+// the asset to.
+func finalTestStage(finalTestResult string) LifecycleStage {
+	if isFailingResult(finalTestResult) {
+		return StageRejected
+	}
+	return StageTested
+}
+
+// inspectionResultIndex is the composite key object type listing inspection
+// events by their primary inspection result and time.
+const inspectionResultIndex = "inspectionResult~result~timestamp~assetID~txID"
+
+// indexInspectionResult records the primary inspection result of the event,
+// already stamped with its txID and timestamp, if it carries one.
+func indexInspectionResult(ctx contractapi.TransactionContextInterface, assetID string, event ProvenanceEvent) error {
+	if event.PrimaryInspectionResult == "" {
+		return nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(inspectionResultIndex, []string{event.PrimaryInspectionResult, event.Timestamp, assetID, event.TxID})
+	if err != nil {
+		return fmt.Errorf("failed to create inspection result index key: %w", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put inspection result index: %w", err)
+	}
+	return nil
+}
+
+// InspectionMatch is an asset together with the inspection event that matched a query.
+type InspectionMatch struct {
+	Asset *Asset          `json:"asset"`
+	Event ProvenanceEvent `json:"event"`
+}
+
+// GetAssetsByInspectionResult returns the inspection events whose primary
+// inspection result is result, PASS, FAIL or REWORK in any spelling
+// RecordInspection accepts, and that were recorded between startTime and
+// endTime inclusive, each with the asset it belongs to, ordered by time. Both
+// bounds are optional RFC3339 timestamps. It reads the inspection result index
+// rather than every asset's events; events of deleted assets and archived
+// events are left out.
+func (s *SmartContract) GetAssetsByInspectionResult(ctx contractapi.TransactionContextInterface, result string, startTime string, endTime string) ([]InspectionMatch, error) {
+	parsedResult, err := parseQualityResult("result", result)
+	if err != nil {
+		return nil, err
+	}
+	start, err := parseTimeBound("startTime", startTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeBound("endTime", endTime)
+	if err != nil {
+		return nil, err
+	}
+	if start != nil && end != nil && start.After(*end) {
+		return nil, fmt.Errorf("%w: startTime %s is after endTime %s", ErrInvalidArgument, startTime, endTime)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(inspectionResultIndex, []string{string(parsedResult)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inspection result index: %w", err)
+	}
+	defer iterator.Close()
+
+	matches := []InspectionMatch{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate inspection result index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split inspection result index key: %w", err)
+		}
+		recorded, err := time.Parse(time.RFC3339, keyParts[1])
+		if err != nil {
+			continue
+		}
+		if (start != nil && recorded.Before(*start)) || (end != nil && recorded.After(*end)) {
+			continue
+		}
+		asset, err := s.ReadAsset(ctx, keyParts[2])
+		if errors.Is(err, ErrAssetNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		event, err := historyEvent(ctx, asset, keyParts[3])
+		if errors.Is(err, ErrInvalidArgument) || errors.Is(err, ErrNotFound) {
+			// The event was archived out of the asset's history.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, InspectionMatch{Asset: asset, Event: *event})
+	}
+	return matches, nil
+}
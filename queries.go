@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetAssetsByOwner returns every asset owned by the given MSPID. Requires the
+// CouchDB state database.
+func (s *SmartContract) GetAssetsByOwner(ctx contractapi.TransactionContextInterface, owner string) ([]*Asset, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("%w: owner must not be empty", ErrInvalidArgument)
+	}
+	query, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"owner": owner,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetQueryResult(string(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets by owner: %w", err)
+	}
+	defer iterator.Close()
+
+	assets := []*Asset{}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate assets by owner: %w", err)
+		}
+		if strings.HasPrefix(result.Key, "EVENT_") {
+			continue
+		}
+		var asset Asset
+		err = json.Unmarshal(result.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, &asset)
+	}
+	return assets, nil
+}
+
+// AssetPage is one page of assets together with the bookmark of the next page.
+type AssetPage struct {
+	Records             []*Asset `json:"records"`
+	FetchedRecordsCount int32    `json:"fetchedRecordsCount"`
+	Bookmark            string   `json:"bookmark"`
+}
+
+// GetAllAssets returns one page of assets, starting at the bookmark returned by
+// the previous page, or at the beginning when bookmark is empty.
+// FetchedRecordsCount counts every record read for the page, including the
+// event records that are filtered out of Records.
+func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("%w: pageSize must be positive, got %d", ErrInvalidArgument, pageSize)
+	}
+	iterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	defer iterator.Close()
+
+	page := &AssetPage{Records: []*Asset{}}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate world state: %w", err)
+		}
+		if strings.HasPrefix(result.Key, "EVENT_") {
+			continue
+		}
+		var asset Asset
+		err = json.Unmarshal(result.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, &asset)
+	}
+	if metadata != nil {
+		page.FetchedRecordsCount = metadata.FetchedRecordsCount
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+// AssetCriteria are the fields QueryAssets can match assets on. Unset fields
+// match any asset; Tags match assets carrying every listed tag with its value.
+type AssetCriteria struct {
+	Owner         string            `json:"owner"`
+	Custodian     string            `json:"custodian"`
+	Stage         LifecycleStage    `json:"stage"`
+	CertificateID string            `json:"certificateID"`
+	InstalledIn   string            `json:"installedIn"`
+	Held          *bool             `json:"held"`
+	Failed        *bool             `json:"failed"`
+	Recalled      *bool             `json:"recalled"`
+	Tags          map[string]string `json:"tags"`
+}
+
+// assetSelector builds the Mango selector matching the criteria. Every value is
+// placed as a literal under a fixed field name, so criteria cannot inject
+// operators or reach other fields.
+func assetSelector(criteria AssetCriteria) (map[string]interface{}, error) {
+	selector := map[string]interface{}{
+		"assetID": map[string]interface{}{"$exists": true},
+	}
+	for field, value := range map[string]string{
+		"owner":                 criteria.Owner,
+		"custodian":             criteria.Custodian,
+		"currentLifecycleStage": string(criteria.Stage),
+		"certificateID":         criteria.CertificateID,
+		"installedIn":           criteria.InstalledIn,
+	} {
+		if value != "" {
+			selector[field] = map[string]interface{}{"$eq": value}
+		}
+	}
+	for field, value := range map[string]*bool{
+		"held":     criteria.Held,
+		"failed":   criteria.Failed,
+		"recalled": criteria.Recalled,
+	} {
+		if value != nil {
+			selector[field] = map[string]interface{}{"$eq": *value}
+		}
+	}
+	for tag, value := range criteria.Tags {
+		if tag == "" || strings.ContainsAny(tag, ".$") {
+			return nil, fmt.Errorf("%w: invalid tag %q: must be non-empty and must not contain '.' or '$'", ErrInvalidArgument, tag)
+		}
+		selector["tags."+tag] = map[string]interface{}{"$eq": value}
+	}
+	return selector, nil
+}
+
+// assetQuery returns the CouchDB query matching criteriaJSON, an AssetCriteria
+// object. Unknown criteria fields are rejected.
+func assetQuery(ctx contractapi.TransactionContextInterface, criteriaJSON string) (string, error) {
+	var criteria AssetCriteria
+	decoder := json.NewDecoder(strings.NewReader(criteriaJSON))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&criteria)
+	if err != nil {
+		return "", fmt.Errorf("%w: criteriaJSON must be an asset criteria object: %v", ErrInvalidArgument, err)
+	}
+	if criteria.Stage != "" {
+		_, err = parseLifecycleStage(ctx, string(criteria.Stage))
+		if err != nil {
+			return "", err
+		}
+	}
+	selector, err := assetSelector(criteria)
+	if err != nil {
+		return "", err
+	}
+	query, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return "", err
+	}
+	return string(query), nil
+}
+
+// queryAssetPage runs the query and returns one page of the matching assets.
+func queryAssetPage(ctx contractapi.TransactionContextInterface, query string, pageSize int32, bookmark string) (*AssetPage, error) {
+	iterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(query, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %w", err)
+	}
+	defer iterator.Close()
+
+	page := &AssetPage{Records: []*Asset{}}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate assets: %w", err)
+		}
+		var asset Asset
+		err = json.Unmarshal(result.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, &asset)
+	}
+	if metadata != nil {
+		page.FetchedRecordsCount = metadata.FetchedRecordsCount
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+// QueryAssets returns one page of the assets matching criteriaJSON, an
+// AssetCriteria object, starting at the bookmark of the previous page. The
+// selector is built server-side and unknown criteria fields are rejected.
+// Event attributes such as supplier or machine are not part of the asset
+// record; use the material batch and supplier indexes for those. Requires the
+// CouchDB state database. QueryAssetsCached serves the same query from a
+// cache of earlier results.
+func (s *SmartContract) QueryAssets(ctx contractapi.TransactionContextInterface, criteriaJSON string, pageSize int32, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("%w: pageSize must be positive, got %d", ErrInvalidArgument, pageSize)
+	}
+	query, err := assetQuery(ctx, criteriaJSON)
+	if err != nil {
+		return nil, err
+	}
+	return queryAssetPage(ctx, query, pageSize, bookmark)
+}
+
+// GetMyAssets returns the assets owned by the caller's MSP, grouped by their
+// current lifecycle stage. Every stage of the default lifecycle is listed,
+// with an empty group when the caller owns no asset in it. Requires the
+// CouchDB state database.
+func (s *SmartContract) GetMyAssets(ctx contractapi.TransactionContextInterface) (map[LifecycleStage][]*Asset, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	assets, err := s.GetAssetsByOwner(ctx, clientMSPID)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[LifecycleStage][]*Asset)
+	for _, targets := range lifecycleTransitions {
+		for _, stage := range targets {
+			groups[stage] = []*Asset{}
+		}
+	}
+	for _, asset := range assets {
+		groups[asset.CurrentLifecycleStage] = append(groups[asset.CurrentLifecycleStage], asset)
+	}
+	return groups, nil
+}
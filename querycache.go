@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// queryCacheIndex is the composite key object type holding cached query
+// results, keyed by the hash of the query.
+const queryCacheIndex = "queryCache~queryHash"
+
+// queryCacheTTL is the longest a cached result is served, even when no event
+// was recorded since it was computed.
+const queryCacheTTL = 60 * time.Second
+
+// QueryCacheEntry is a cached result of QueryAssetsCached. Fabric exposes no
+// block height to chaincode, so the entry records the anchor event sequence
+// number instead: it advances with every committed transaction recording an
+// event, in commit order.
+type QueryCacheEntry struct {
+	EventSequence int      `json:"eventSequence"`
+	ComputedAt    string   `json:"computedAt"`
+	AssetIDs      []string `json:"assetIDs"`
+}
+
+// queryCacheKey returns the state key caching the result of query.
+func queryCacheKey(ctx contractapi.TransactionContextInterface, query string) (string, error) {
+	hash := sha256.Sum256([]byte(query))
+	key, err := ctx.GetStub().CreateCompositeKey(queryCacheIndex, []string{hex.EncodeToString(hash[:])})
+	if err != nil {
+		return "", fmt.Errorf("failed to create query cache key: %w", err)
+	}
+	return key, nil
+}
+
+// freshQueryCacheEntry returns the entry cached under key, or nil when there
+// is none or it is stale: an event was recorded since it was computed, or it
+// is older than queryCacheTTL.
+func freshQueryCacheEntry(ctx contractapi.TransactionContextInterface, key string, sequence int) (*QueryCacheEntry, error) {
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query cache: %w", err)
+	}
+	if entryJSON == nil {
+		return nil, nil
+	}
+	var entry QueryCacheEntry
+	if json.Unmarshal(entryJSON, &entry) != nil || entry.EventSequence != sequence {
+		return nil, nil
+	}
+	computedAt, err := time.Parse(time.RFC3339, entry.ComputedAt)
+	if err != nil {
+		return nil, nil
+	}
+	now, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %w", err)
+	}
+	if age := now.AsTime().Sub(computedAt); age < 0 || age > queryCacheTTL {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// queryAssetIDs runs the query without pagination, which unlike a paginated
+// query leaves the transaction free to write, and returns the matching asset IDs.
+func queryAssetIDs(ctx contractapi.TransactionContextInterface, query string) ([]string, error) {
+	iterator, err := ctx.GetStub().GetQueryResult(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %w", err)
+	}
+	defer iterator.Close()
+
+	assetIDs := []string{}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate assets: %w", err)
+		}
+		assetIDs = append(assetIDs, result.Key)
+	}
+	return assetIDs, nil
+}
+
+// QueryAssetsCached returns the assets matching criteriaJSON, like
+// QueryAssets, served from a cache of the earlier result of the same query
+// when it is fresh. The bookmark of a page is the position of its first asset
+// in the result. The cached result is dropped as soon as any transaction
+// recording an event commits, since the asset it wrote may now match, and in
+// any case after queryCacheTTL; within that window it may miss an asset
+// written without recording an event, and the records it lists are always
+// read at their current state. The query and the cache are only refreshed
+// when the transaction is submitted, so submit it when a page comes back
+// stale and evaluate it in between. Requires the CouchDB state database.
+func (s *SmartContract) QueryAssetsCached(ctx contractapi.TransactionContextInterface, criteriaJSON string, pageSize int32, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("%w: pageSize must be positive, got %d", ErrInvalidArgument, pageSize)
+	}
+	offset := 0
+	if bookmark != "" {
+		var err error
+		offset, err = strconv.Atoi(bookmark)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("%w: bookmark %q was not returned by QueryAssetsCached", ErrInvalidArgument, bookmark)
+		}
+	}
+	query, err := assetQuery(ctx, criteriaJSON)
+	if err != nil {
+		return nil, err
+	}
+	key, err := queryCacheKey(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	sequence, err := nextEventSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := freshQueryCacheEntry(ctx, key, sequence)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		assetIDs, err := queryAssetIDs(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		computedAt, err := txTimestamp(ctx)
+		if err != nil {
+			return nil, err
+		}
+		entry = &QueryCacheEntry{EventSequence: sequence, ComputedAt: computedAt, AssetIDs: assetIDs}
+		entryJSON, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().PutState(key, entryJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put query cache: %w", err)
+		}
+	}
+
+	page := &AssetPage{Records: []*Asset{}}
+	next := offset
+	for ; next < len(entry.AssetIDs) && len(page.Records) < int(pageSize); next++ {
+		asset, err := s.ReadAsset(ctx, entry.AssetIDs[next])
+		if errors.Is(err, ErrAssetNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, asset)
+	}
+	page.FetchedRecordsCount = int32(len(page.Records))
+	if next < len(entry.AssetIDs) {
+		page.Bookmark = strconv.Itoa(next)
+	}
+	return page, nil
+}
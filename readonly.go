@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// evaluateTransactions lists the read-only transactions of the contract. They
+// never write state or emit events, so clients should call them with Evaluate
+// (a single-peer query) rather than Submit. The list is published in the
+// contract metadata, and enforceReadOnly rejects any write they attempt.
+var evaluateTransactions = []string{
+	"AssetExists",
+	"CheckIntegrity",
+	"ComputeAnchorDigest",
+	"ExportAsset",
+	"GenerateProvenanceProof",
+	"GetAllAssets",
+	"GetAllowedTransitions",
+	"GetAssetByCertificate",
+	"GetAssetDiff",
+	"GetAssetEndorsementPolicy",
+	"GetAssetHistory",
+	"GetAssetHistoryPaginated",
+	"GetAssetLineage",
+	"GetAssetSnapshot",
+	"GetAssetStateHistory",
+	"GetAssetStatistics",
+	"GetAssetsByInspectionResult",
+	"GetAssetsByMachine",
+	"GetAssetsByMaterialBatch",
+	"GetAssetsByOwner",
+	"GetAssetsByStage",
+	"GetAssetsByTag",
+	"GetAssetsCreatedBetween",
+	"GetAssetsReadyForCertification",
+	"GetAuthorizedMSPs",
+	"GetCertificateValidity",
+	"GetCertificationPolicy",
+	"GetCurrentHolder",
+	"GetCustodyChain",
+	"GetEvent",
+	"GetEventsByAgent",
+	"GetFailuresByMaterialBatch",
+	"GetLifecycle",
+	"GetMaxPayloadSize",
+	"GetMultiAssetHistory",
+	"GetMyAssets",
+	"GetOffChainHashRequirement",
+	"GetOwnershipHistory",
+	"GetPartsInstalledIn",
+	"GetPayloadSchema",
+	"GetRequiredAttribute",
+	"GetRequiredFields",
+	"GetStageCount",
+	"GetStalledAssets",
+	"GetSupplierSummary",
+	"GetSupplyChainParticipants",
+	"GetTimestampAnomalies",
+	"QueryAssetHistory",
+	"QueryAssets",
+	"ReadAsset",
+	"ReadPrivateDetails",
+	"ValidateEvent",
+	"VerifyArchivedEvent",
+	"VerifyCertificate",
+	"VerifyHistoryChain",
+	"VerifyOffChainData",
+}
+
+var (
+	_ contractapi.EvaluationContractInterface = (*SmartContract)(nil)
+	_ shim.ChaincodeStubInterface             = (*readOnlyStub)(nil)
+)
+
+// GetEvaluateTransactions returns the transactions tagged as evaluate in the metadata.
+func (s *SmartContract) GetEvaluateTransactions() []string {
+	return evaluateTransactions
+}
+
+// GetBeforeTransaction returns the hook run ahead of every transaction.
+func (s *SmartContract) GetBeforeTransaction() interface{} {
+	return enforceReadOnly
+}
+
+// enforceReadOnly swaps the stub of an evaluate transaction for one that
+// refuses every ledger write, so a read function that starts writing state
+// fails loudly instead of silently depending on Submit.
+func enforceReadOnly(ctx contractapi.TransactionContextInterface) error {
+	function, _ := ctx.GetStub().GetFunctionAndParameters()
+	if i := strings.LastIndex(function, ":"); i != -1 {
+		function = function[i+1:]
+	}
+	if !isEvaluateTransaction(function) {
+		return nil
+	}
+	settable, ok := ctx.(contractapi.SettableTransactionContextInterface)
+	if !ok {
+		return fmt.Errorf("transaction context does not allow guarding read-only transaction %s", function)
+	}
+	settable.SetStub(&readOnlyStub{ChaincodeStubInterface: ctx.GetStub(), function: function})
+	return nil
+}
+
+// isEvaluateTransaction reports whether the named transaction is read-only.
+func isEvaluateTransaction(function string) bool {
+	for _, name := range evaluateTransactions {
+		if strings.EqualFold(name, function) {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyStub wraps the chaincode stub of an evaluate transaction and rejects
+// every call that would modify the ledger or emit a chaincode event.
+type readOnlyStub struct {
+	shim.ChaincodeStubInterface
+	function string
+}
+
+func (r *readOnlyStub) reject(operation string) error {
+	return fmt.Errorf("%w: read-only transaction %s attempted %s", ErrUnauthorized, r.function, operation)
+}
+
+func (r *readOnlyStub) PutState(key string, value []byte) error {
+	return r.reject("PutState")
+}
+
+func (r *readOnlyStub) DelState(key string) error {
+	return r.reject("DelState")
+}
+
+func (r *readOnlyStub) SetStateValidationParameter(key string, ep []byte) error {
+	return r.reject("SetStateValidationParameter")
+}
+
+func (r *readOnlyStub) PutPrivateData(collection string, key string, value []byte) error {
+	return r.reject("PutPrivateData")
+}
+
+func (r *readOnlyStub) DelPrivateData(collection, key string) error {
+	return r.reject("DelPrivateData")
+}
+
+func (r *readOnlyStub) PurgePrivateData(collection, key string) error {
+	return r.reject("PurgePrivateData")
+}
+
+func (r *readOnlyStub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	return r.reject("SetPrivateDataValidationParameter")
+}
+
+func (r *readOnlyStub) SetEvent(name string, payload []byte) error {
+	return r.reject("SetEvent")
+}
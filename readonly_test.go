@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	testAdminMSP    = "AdminMSP"
+	testSupplierMSP = "SupplierMSP"
+	testHash        = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+)
+
+var (
+	operator = map[string]string{roleAttribute: "operator"}
+	qa       = map[string]string{roleAttribute: "qa"}
+)
+
+// seedLedger records a material batch and a part printed from it that has
+// passed inspection and final test under certificate CERT-1.
+func seedLedger(t *testing.T) *fakeLedger {
+	t.Helper()
+	s := new(SmartContract)
+	l := newFakeLedger()
+	l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return s.InitLedger(ctx, testAdminMSP)
+	})
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return s.CreateMaterialCertification(ctx, "MAT-1", "Ti-6Al-4V", "BATCH-1", "SUP-1", testHash, "", "")
+	})
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return s.CreateMaterialCertification(ctx, "PART-1", "Ti-6Al-4V", "BATCH-1", "SUP-1", testHash, "", "")
+	})
+	l.submit(t, testSupplierMSP, operator, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RecordPrintJob(ctx, "PART-1", "JOB-1", "MACHINE-1", "MAT-1", 0, testHash, testHash, testHash, "")
+		return err
+	})
+	l.submit(t, testSupplierMSP, qa, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RecordInspection(ctx, "PART-1", "PASS", "", testHash, "")
+		return err
+	})
+	l.submit(t, testSupplierMSP, qa, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RecordFinalTest(ctx, "PART-1", "ASTM F2924", "PASS", "", "CERT-1", testHash, "")
+		return err
+	})
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.SetAssetTags(ctx, "PART-1", `{"program":"demo"}`, "")
+		return err
+	})
+	return l
+}
+
+// seedEvaluateLedger extends seedLedger so that every evaluate transaction has
+// something to read: PART-1 is certified and installed in ENGINE-1, the
+// creation of MAT-1 is archived, and MAT-2 keeps private details in
+// supplierCollection. It returns the ledger, the txID of the archive summary
+// and the archived event as it was returned before archival.
+func seedEvaluateLedger(t *testing.T) (*fakeLedger, string, string) {
+	t.Helper()
+	s := new(SmartContract)
+	l := seedLedger(t)
+	l.submit(t, testSupplierMSP, qa, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.IssueCertificate(ctx, "PART-1", "CERT-1", "ASTM F2924", testHash, "")
+		return err
+	})
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RecordInstallation(ctx, "PART-1", "ENGINE-1", "stage 1", testHash, "")
+		return err
+	})
+	var eventJSON []byte
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		event, err := s.GetEvent(ctx, "MAT-1", "tx0002")
+		if err != nil {
+			return err
+		}
+		eventJSON, err = json.Marshal(event)
+		return err
+	})
+	var summaryTxID string
+	l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+		summaryTxID = ctx.GetStub().GetTxID()
+		return s.ArchiveHistory(ctx, "MAT-1", "2024-01-01T00:02:30Z")
+	})
+	ctx, stub := l.context("", testSupplierMSP, nil)
+	stub.transient = map[string][]byte{privateDetailsTransientKey: []byte(`{"supplierID":"SUP-1","payload":"price list"}`)}
+	err := s.CreateMaterialCertificationPrivate(ctx, "supplierCollection", "MAT-2", "Ti-6Al-4V", "BATCH-2", testHash)
+	if err != nil {
+		t.Fatalf("transaction %s failed: %v", stub.GetTxID(), err)
+	}
+	return l, summaryTxID, string(eventJSON)
+}
+
+// TestEvaluateTransactionsDoNotWrite runs every evaluate transaction behind
+// readOnlyStub on a seeded ledger and fails on any write or event it attempts,
+// or on any error it returns.
+func TestEvaluateTransactionsDoNotWrite(t *testing.T) {
+	t.Setenv("CORE_PEER_LOCALMSPID", testSupplierMSP)
+	l, summaryTxID, archivedEventJSON := seedEvaluateLedger(t)
+	args := map[string][]interface{}{
+		"AssetExists":                    {"PART-1"},
+		"CheckIntegrity":                 {"PART-1"},
+		"ComputeAnchorDigest":            {0},
+		"ExportAsset":                    {"PART-1"},
+		"GenerateProvenanceProof":        {"PART-1"},
+		"GetAllAssets":                   {int32(10), ""},
+		"GetAllowedTransitions":          {string(StagePrinted)},
+		"GetAssetByCertificate":          {"CERT-1"},
+		"GetAssetDiff":                   {"PART-1", "tx0004", "tx0006"},
+		"GetAssetEndorsementPolicy":      {"PART-1"},
+		"GetAssetHistory":                {"PART-1"},
+		"GetAssetHistoryPaginated":       {"PART-1", 0, 2},
+		"GetAssetLineage":                {"PART-1"},
+		"GetAssetSnapshot":               {"PART-1"},
+		"GetAssetStateHistory":           {"PART-1"},
+		"GetAssetStatistics":             {},
+		"GetAssetsByInspectionResult":    {"pass", "", ""},
+		"GetAssetsByMachine":             {"MACHINE-1"},
+		"GetAssetsByMaterialBatch":       {"MAT-1"},
+		"GetAssetsByOwner":               {testSupplierMSP},
+		"GetAssetsByStage":               {string(StageTested)},
+		"GetAssetsByTag":                 {"program", "demo"},
+		"GetAssetsCreatedBetween":        {"2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z"},
+		"GetAssetsReadyForCertification": {},
+		"GetAuthorizedMSPs":              {string(EventPrintJob)},
+		"GetCertificateValidity":         {"CERT-1"},
+		"GetCertificationPolicy":         {},
+		"GetCurrentHolder":               {"PART-1"},
+		"GetCustodyChain":                {"PART-1"},
+		"GetEvent":                       {"PART-1", "tx0004"},
+		"GetEventsByAgent":               {testSupplierMSP, "", ""},
+		"GetFailuresByMaterialBatch":     {"BATCH-1"},
+		"GetLifecycle":                   {},
+		"GetMaxPayloadSize":              {},
+		"GetMultiAssetHistory":           {[]string{"MAT-1", "PART-1"}},
+		"GetMyAssets":                    {},
+		"GetOffChainHashRequirement":     {string(EventInspection)},
+		"GetOwnershipHistory":            {"PART-1"},
+		"GetPartsInstalledIn":            {"ENGINE-1"},
+		"GetPayloadSchema":               {string(EventInspection)},
+		"GetRequiredAttribute":           {string(EventFinalTest)},
+		"GetRequiredFields":              {string(EventPrintJob)},
+		"GetStageCount":                  {string(StageTested)},
+		"GetStalledAssets":               {string(StageTested), 0},
+		"GetSupplierSummary":             {"SUP-1"},
+		"GetSupplyChainParticipants":     {"PART-1"},
+		"GetTimestampAnomalies":          {"PART-1"},
+		"QueryAssetHistory":              {"PART-1", "", "", ""},
+		"QueryAssets":                    {`{"stage":"TESTED"}`, int32(10), ""},
+		"ReadAsset":                      {"PART-1"},
+		"ReadPrivateDetails":             {"supplierCollection", "MAT-2"},
+		"ValidateEvent":                  {"PART-1", string(EventPostProcessed), `{}`},
+		"VerifyArchivedEvent":            {"MAT-1", summaryTxID, archivedEventJSON},
+		"VerifyCertificate":              {"CERT-1", testHash},
+		"VerifyHistoryChain":             {"PART-1"},
+		"VerifyOffChainData":             {"PART-1", "tx0004", testHash},
+	}
+	contract := reflect.ValueOf(new(SmartContract))
+	for _, name := range evaluateTransactions {
+		t.Run(name, func(t *testing.T) {
+			callArgs, ok := args[name]
+			if !ok {
+				t.Fatalf("no arguments listed for evaluate transaction %s", name)
+			}
+			method := contract.MethodByName(name)
+			if !method.IsValid() {
+				t.Fatalf("evaluate transaction %s is not a method of the contract", name)
+			}
+			ctx, _ := l.context(name, testSupplierMSP, qa)
+			err := enforceReadOnly(ctx)
+			if err != nil {
+				t.Fatalf("enforceReadOnly: %v", err)
+			}
+			guard, ok := ctx.GetStub().(*readOnlyStub)
+			if !ok {
+				t.Fatalf("%s did not run behind readOnlyStub", name)
+			}
+			recorder := &writeRecorder{ChaincodeStubInterface: guard}
+			ctx.SetStub(recorder)
+			in := []reflect.Value{reflect.ValueOf(ctx)}
+			for _, arg := range callArgs {
+				in = append(in, reflect.ValueOf(arg))
+			}
+			out := method.Call(in)
+			if len(recorder.attempts) != 0 {
+				t.Fatalf("%s attempted %v", name, recorder.attempts)
+			}
+			if err, ok := out[len(out)-1].Interface().(error); ok && err != nil {
+				t.Fatalf("%s failed: %v", name, err)
+			}
+		})
+	}
+}
+
+// writeRecorder sits in front of readOnlyStub and notes every write or event
+// attempted, so a transaction that swallows the rejection still fails the test.
+type writeRecorder struct {
+	shim.ChaincodeStubInterface
+	attempts []string
+}
+
+func (r *writeRecorder) PutState(key string, value []byte) error {
+	r.attempts = append(r.attempts, "PutState "+key)
+	return r.ChaincodeStubInterface.PutState(key, value)
+}
+
+func (r *writeRecorder) DelState(key string) error {
+	r.attempts = append(r.attempts, "DelState "+key)
+	return r.ChaincodeStubInterface.DelState(key)
+}
+
+func (r *writeRecorder) SetStateValidationParameter(key string, ep []byte) error {
+	r.attempts = append(r.attempts, "SetStateValidationParameter "+key)
+	return r.ChaincodeStubInterface.SetStateValidationParameter(key, ep)
+}
+
+func (r *writeRecorder) PutPrivateData(collection string, key string, value []byte) error {
+	r.attempts = append(r.attempts, "PutPrivateData "+key)
+	return r.ChaincodeStubInterface.PutPrivateData(collection, key, value)
+}
+
+func (r *writeRecorder) DelPrivateData(collection string, key string) error {
+	r.attempts = append(r.attempts, "DelPrivateData "+key)
+	return r.ChaincodeStubInterface.DelPrivateData(collection, key)
+}
+
+func (r *writeRecorder) PurgePrivateData(collection string, key string) error {
+	r.attempts = append(r.attempts, "PurgePrivateData "+key)
+	return r.ChaincodeStubInterface.PurgePrivateData(collection, key)
+}
+
+func (r *writeRecorder) SetEvent(name string, payload []byte) error {
+	r.attempts = append(r.attempts, "SetEvent "+name)
+	return r.ChaincodeStubInterface.SetEvent(name, payload)
+}
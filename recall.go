@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RecallPayload is the on-chain payload of a RECALLED event.
+type RecallPayload struct {
+	RootAssetID string `json:"rootAssetID"`
+	Reason      string `json:"reason"`
+}
+
+// recallScope returns rootAssetID's asset followed by every asset descending
+// from it in the genealogy or made from it as material, each listed once, so
+// a cycle in the links cannot loop the walk. Parts made from a material are
+// found through the material batch index.
+func (s *SmartContract) recallScope(ctx contractapi.TransactionContextInterface, root *Asset) ([]*Asset, error) {
+	scope := []*Asset{}
+	seen := map[string]bool{root.AssetID: true}
+	queue := []*Asset{root}
+	for len(queue) > 0 {
+		asset := queue[0]
+		queue = queue[1:]
+		scope = append(scope, asset)
+		madeFrom, err := s.GetAssetsByMaterialBatch(ctx, asset.AssetID)
+		if err != nil {
+			return nil, err
+		}
+		for _, childID := range append(append([]string{}, asset.ChildAssetIDs...), madeFrom...) {
+			if seen[childID] {
+				continue
+			}
+			seen[childID] = true
+			child, err := s.ReadAsset(ctx, childID)
+			if errors.Is(err, ErrAssetNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			queue = append(queue, child)
+		}
+	}
+	return scope, nil
+}
+
+// InitiateRecall recalls rootAssetID and every asset descending from it in the
+// genealogy or made from it as material, recording a RECALLED event on each
+// and setting its Recalled flag, and returns the IDs of the assets recalled.
+// Parts made from a material are found through the material batch index, so
+// the parts printed from a recalled batch are recalled with it. Assets already
+// recalled or in a terminal stage are left untouched. Only the owner of the
+// root asset or the admin MSP may initiate a recall. A retry with the
+// clientEventID of an earlier recall of the root returns the assets that
+// recall recorded its event on, and records nothing.
+func (s *SmartContract) InitiateRecall(ctx contractapi.TransactionContextInterface, rootAssetID string, reason string, clientEventID string) ([]string, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a recall reason is required", ErrInvalidArgument)
+	}
+	root, err := s.ReadAsset(ctx, rootAssetID)
+	if err != nil {
+		return nil, err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	priorTxID, err := priorClientEvent(ctx, rootAssetID, clientEventID)
+	if err != nil {
+		return nil, err
+	}
+	if priorTxID == "" && root.Recalled {
+		return nil, fmt.Errorf("%w: the asset %s is already recalled", ErrInvalidState, rootAssetID)
+	}
+	scope, err := s.recallScope(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	recalled := []string{}
+	if priorTxID != "" {
+		for _, asset := range scope {
+			for _, txID := range asset.HistoryTxIDs {
+				if txID == priorTxID {
+					recalled = append(recalled, asset.AssetID)
+					break
+				}
+			}
+		}
+		return recalled, nil
+	}
+
+	payload, err := json.Marshal(RecallPayload{RootAssetID: rootAssetID, Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventRecalled,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+	}
+	for _, asset := range scope {
+		terminal, err := isTerminalStage(ctx, asset.CurrentLifecycleStage)
+		if err != nil {
+			return nil, err
+		}
+		if asset.Recalled || terminal {
+			continue
+		}
+		asset.Recalled = true
+		err = s.appendAssetEvent(ctx, asset, event, "")
+		if err != nil {
+			return nil, err
+		}
+		recalled = append(recalled, asset.AssetID)
+	}
+	err = emitChaincodeEvent(ctx, event.EventType, recalled...)
+	if err != nil {
+		return nil, err
+	}
+	return recalled, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReprintPayload is the on-chain payload of a REPRINT event.
+type ReprintPayload struct {
+	FailedAssetID      string `json:"failedAssetID"`
+	ReplacementAssetID string `json:"replacementAssetID"`
+}
+
+// RecordReprint creates newAssetID as the reprint of a part that was rejected
+// or failed in service, moving it straight to PRINTED, and records a REPRINT
+// event referencing both parts on each of them. Only the owner of the failed
+// part may reprint it. The material is checked and consumed as by
+// RecordPrintJob: a positive materialAmount is deducted from the materialUsedID
+// batch, and expired material is rejected. It returns the txID that recorded
+// the reprint, or the one that already recorded clientEventID for the failed
+// part.
+func (s *SmartContract) RecordReprint(ctx contractapi.TransactionContextInterface, failedAssetID string, newAssetID string, printJobID string, machineID string, materialUsedID string, materialAmount float64, offChainDataHash string, clientEventID string) (string, error) {
+	err := validateAssetID(newAssetID)
+	if err != nil {
+		return "", err
+	}
+	err = checkPrintJobArguments(newAssetID, printJobID, machineID, materialUsedID, materialAmount, "", "")
+	if err != nil {
+		return "", err
+	}
+	if materialUsedID != "" && materialUsedID == failedAssetID {
+		return "", fmt.Errorf("%w: a part cannot be reprinted from the part it replaces", ErrInvalidArgument)
+	}
+	failed, err := s.ReadAsset(ctx, failedAssetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwner(ctx, failed)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, failedAssetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	if failed.CurrentLifecycleStage != StageRejected && !failed.Failed {
+		return "", fmt.Errorf("%w: the asset %s is in stage %s and has not failed", ErrInvalidState, failedAssetID, failed.CurrentLifecycleStage)
+	}
+	exists, err := s.AssetExists(ctx, newAssetID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("%w: the asset %s already exists", ErrAssetExists, newAssetID)
+	}
+	err = s.checkPrintMaterial(ctx, materialUsedID, materialAmount)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(ReprintPayload{FailedAssetID: failedAssetID, ReplacementAssetID: newAssetID})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventReprint,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payload),
+		PrintJobID:         printJobID,
+		MachineID:          machineID,
+		MaterialUsedID:     materialUsedID,
+	}
+	replacement := &Asset{
+		AssetID:      newAssetID,
+		Owner:        clientMSPID,
+		HistoryTxIDs: []string{},
+	}
+	assetIDs := []string{newAssetID, failedAssetID}
+	if materialAmount > 0 {
+		err = s.consumeMaterial(ctx, materialUsedID, materialAmount, newAssetID, printJobID)
+		if err != nil {
+			return "", err
+		}
+		assetIDs = append(assetIDs, materialUsedID)
+	}
+	err = s.appendAssetEvent(ctx, replacement, event, StagePrinted)
+	if err != nil {
+		return "", err
+	}
+	err = s.appendAssetEvent(ctx, failed, event, "")
+	if err != nil {
+		return "", err
+	}
+	err = emitChaincodeEvent(ctx, event.EventType, assetIDs...)
+	if err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
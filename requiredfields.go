@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// requiredFieldsConfig names the configuration entries holding the event
+// fields each event type requires.
+const requiredFieldsConfig = "requiredFields"
+
+// contractSetEventFields are the event fields the contract fills in itself when
+// recording an event, which a required-fields policy cannot name.
+var contractSetEventFields = map[string]bool{
+	"schemaVersion":     true,
+	"eventType":         true,
+	"txID":              true,
+	"agentID":           true,
+	"timestamp":         true,
+	"previousEventHash": true,
+}
+
+// requiredFields returns the event fields, by JSON name, that events of
+// eventType must carry. No field is required unless configured, beyond the
+// arguments each transaction checks itself.
+func requiredFields(ctx contractapi.TransactionContextInterface, eventType EventType) ([]string, error) {
+	value, err := getConfig(ctx, requiredFieldsConfig, string(eventType))
+	if err != nil || value == nil {
+		return []string{}, err
+	}
+	var fields []string
+	err = json.Unmarshal(value, &fields)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// isEmptyField reports whether the JSON value of an event field is absent or
+// its zero value.
+func isEmptyField(value json.RawMessage) bool {
+	switch string(value) {
+	case "", "null", `""`, "0":
+		return true
+	}
+	return false
+}
+
+// requiredFieldsValidator rejects an event missing any field its event type
+// requires, listing every missing field.
+type requiredFieldsValidator struct{}
+
+func (requiredFieldsValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	fields, err := requiredFields(ctx, pending.Event.EventType)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+	values, err := jsonFields(pending.Event)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, field := range fields {
+		if isEmptyField(values[field]) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: events of type %s are missing required fields: %s", ErrInvalidArgument, pending.Event.EventType, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// SetRequiredFields configures the event fields, by JSON name such as
+// machineID, that events of eventType must carry, replacing any previous
+// policy. An empty list lifts the policy. Fields filled in by the contract,
+// such as txID or timestamp, cannot be required. Only the admin MSP may change
+// it.
+func (s *SmartContract) SetRequiredFields(ctx contractapi.TransactionContextInterface, eventType string, fields []string) error {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]bool)
+	for _, field := range fields {
+		if !hasJSONField(ProvenanceEvent{}, field) {
+			return fmt.Errorf("%w: unknown event field %s", ErrInvalidArgument, field)
+		}
+		if contractSetEventFields[field] {
+			return fmt.Errorf("%w: the event field %s is set by the contract and cannot be required", ErrInvalidArgument, field)
+		}
+		listed[field] = true
+	}
+	err = requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	policy := make([]string, 0, len(listed))
+	for field := range listed {
+		policy = append(policy, field)
+	}
+	sort.Strings(policy)
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, policyJSON, requiredFieldsConfig, string(typ))
+}
+
+// GetRequiredFields returns the event fields events of eventType must carry.
+func (s *SmartContract) GetRequiredFields(ctx contractapi.TransactionContextInterface, eventType string) ([]string, error) {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	return requiredFields(ctx, typ)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// roleConfig names the configuration entries of the role registry, listing the
+// MSPs allowed to record each event type.
+const roleConfig = "role"
+
+// authorizedMSPs returns the MSPs registered for an event type. An event type
+// without registered MSPs may be recorded by any organization.
+func authorizedMSPs(ctx contractapi.TransactionContextInterface, eventType EventType) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(configObjectType, []string{roleConfig, string(eventType)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query role registry: %w", err)
+	}
+	defer iterator.Close()
+
+	mspIDs := []string{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate role registry: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split role key: %w", err)
+		}
+		mspIDs = append(mspIDs, keyParts[2])
+	}
+	return mspIDs, nil
+}
+
+// roleValidator rejects an event recorded by an organization the role registry
+// does not allow to record its event type.
+type roleValidator struct{}
+
+func (roleValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	mspIDs, err := authorizedMSPs(ctx, pending.Event.EventType)
+	if err != nil {
+		return err
+	}
+	if len(mspIDs) == 0 {
+		return nil
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	for _, mspID := range mspIDs {
+		if mspID == clientMSPID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: client from %s is not authorized to record %s events", ErrUnauthorized, clientMSPID, pending.Event.EventType)
+}
+
+// RegisterRole authorizes an MSP to record events of eventType. Once an event
+// type has a registered MSP, only registered MSPs may record it. Only the
+// admin MSP may register roles.
+func (s *SmartContract) RegisterRole(ctx contractapi.TransactionContextInterface, eventType string, mspID string) error {
+	if mspID == "" {
+		return fmt.Errorf("%w: mspID must not be empty", ErrInvalidArgument)
+	}
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	err = requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte{0x00}, roleConfig, string(typ), mspID)
+}
+
+// GetAuthorizedMSPs returns the MSPs registered to record events of eventType.
+// An empty list means any organization may record them.
+func (s *SmartContract) GetAuthorizedMSPs(ctx contractapi.TransactionContextInterface, eventType string) ([]string, error) {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	mspIDs, err := authorizedMSPs(ctx, typ)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(mspIDs)
+	return mspIDs, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// currentSchemaVersion is the version of the Asset and ProvenanceEvent records
+// written by this contract. Records written before versioning read as 0.
+const currentSchemaVersion = 3
+
+// canonicalHashSchemaVersion is the first schema version whose events chain to
+// the canonical JSON hash of their predecessor.
+const canonicalHashSchemaVersion = 2
+
+// assetUpgrades fill in the defaults a record of the indexed schema version
+// lacks, moving it to the next version.
+var assetUpgrades = []func(asset *Asset){
+	// 0 -> 1: records written before versioning may lack the history list.
+	func(asset *Asset) {
+		if asset.HistoryTxIDs == nil {
+			asset.HistoryTxIDs = []string{}
+		}
+	},
+	// 1 -> 2: only the event hash chain changed, to canonical JSON hashes.
+	func(asset *Asset) {},
+	// 2 -> 3: only the event encoding changed, to typed events.
+	func(asset *Asset) {},
+}
+
+// upgradeAsset applies the pending upgrades to an asset record read from an
+// older schema version. Every write goes through it, so no record is stored
+// with a current version number but missing defaults. A version outside the
+// known range cannot be upgraded.
+func upgradeAsset(asset *Asset) error {
+	if asset.SchemaVersion < 0 || asset.SchemaVersion > currentSchemaVersion {
+		return fmt.Errorf("%w: the asset %s has schema version %d, outside 0 to %d", ErrInvalidState, asset.AssetID, asset.SchemaVersion, currentSchemaVersion)
+	}
+	for version := asset.SchemaVersion; version < currentSchemaVersion; version++ {
+		assetUpgrades[version](asset)
+	}
+	asset.SchemaVersion = currentSchemaVersion
+	return nil
+}
+
+// UpgradeAsset migrates an asset record written by an older schema version to
+// the current one, rewrites it and returns the version it was upgraded from.
+// Only the owner or the admin MSP may upgrade an asset.
+func (s *SmartContract) UpgradeAsset(ctx contractapi.TransactionContextInterface, assetID string) (int, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return 0, err
+	}
+	if asset.SchemaVersion >= currentSchemaVersion {
+		return 0, fmt.Errorf("%w: the asset %s is already at schema version %d", ErrInvalidState, assetID, asset.SchemaVersion)
+	}
+	_, err = requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	fromVersion := asset.SchemaVersion
+	err = s.putAsset(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	return fromVersion, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// MaterialWriteOff is the quantity of a material batch written off as waste
+// when a part printed from it was scrapped.
+type MaterialWriteOff struct {
+	MaterialID string  `json:"materialID"`
+	Amount     float64 `json:"amount"`
+}
+
+// ScrapPayload is the on-chain payload of a SCRAP event. WrittenOffQuantity is
+// the remaining quantity of a scrapped material batch, and MaterialWriteOffs
+// the material consumed by a scrapped part.
+type ScrapPayload struct {
+	Reason             string             `json:"reason"`
+	WrittenOffQuantity float64            `json:"writtenOffQuantity,omitempty" metadata:",optional"`
+	MaterialWriteOffs  []MaterialWriteOff `json:"materialWriteOffs,omitempty" metadata:",optional"`
+}
+
+// MaterialWriteOffPayload is the on-chain payload of a MATERIAL_WRITTEN_OFF
+// event recorded against a material batch.
+type MaterialWriteOffPayload struct {
+	ScrappedAssetID string  `json:"scrappedAssetID"`
+	Amount          float64 `json:"amount"`
+	Reason          string  `json:"reason"`
+}
+
+// consumedBy returns the quantity of the material batch consumed by the print
+// jobs of printedAssetID.
+func consumedBy(ctx contractapi.TransactionContextInterface, material *Asset, printedAssetID string) float64 {
+	consumed := 0.0
+	for _, event := range assetEvents(ctx, material) {
+		if event.EventType != EventMaterialConsumed {
+			continue
+		}
+		var consumption MaterialConsumptionPayload
+		if json.Unmarshal([]byte(event.OnChainDataPayload), &consumption) != nil || consumption.PrintedAssetID != printedAssetID {
+			continue
+		}
+		consumed += consumption.Amount
+	}
+	return consumed
+}
+
+// RecordScrap scraps an asset mid-process, records a SCRAP event and moves it
+// to the terminal SCRAPPED stage. An installed part must be disassembled
+// first, and an asset already in a terminal stage cannot be scrapped. The
+// material accounted to the asset is written off as waste: a scrapped material
+// batch has its remaining quantity written off, and the quantity a scrapped
+// part consumed from each tracked batch is added to that batch's scrapped
+// quantity through a MATERIAL_WRITTEN_OFF event. Only the owner or the admin
+// MSP may scrap an asset. It returns the txID that recorded the scrap, or the
+// one that already recorded clientEventID for the asset.
+func (s *SmartContract) RecordScrap(ctx contractapi.TransactionContextInterface, assetID string, reason string, offChainDataHash string, clientEventID string) (string, error) {
+	if reason == "" {
+		return "", fmt.Errorf("%w: a reason is required", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	payload := ScrapPayload{Reason: reason}
+	if asset.RemainingQuantity > 0 {
+		payload.WrittenOffQuantity = asset.RemainingQuantity
+		asset.ScrappedQuantity += asset.RemainingQuantity
+		asset.RemainingQuantity = 0
+	}
+
+	var materials []*Asset
+	seen := make(map[string]bool)
+	for _, event := range assetEvents(ctx, asset) {
+		if (event.EventType != EventPrintJob && event.EventType != EventPrintJobLegacy) || event.MaterialUsedID == "" || seen[event.MaterialUsedID] {
+			continue
+		}
+		seen[event.MaterialUsedID] = true
+		material, err := s.ReadAsset(ctx, event.MaterialUsedID)
+		if errors.Is(err, ErrAssetNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		amount := consumedBy(ctx, material, assetID)
+		if amount == 0 {
+			continue
+		}
+		material.ScrappedQuantity += amount
+		materials = append(materials, material)
+		payload.MaterialWriteOffs = append(payload.MaterialWriteOffs, MaterialWriteOff{MaterialID: material.AssetID, Amount: amount})
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventScrap,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payloadJSON),
+	}
+	err = s.appendAssetEvent(ctx, asset, event, StageScrapped)
+	if err != nil {
+		return "", err
+	}
+	assetIDs := []string{assetID}
+	for i, material := range materials {
+		writeOffJSON, err := json.Marshal(MaterialWriteOffPayload{
+			ScrappedAssetID: assetID,
+			Amount:          payload.MaterialWriteOffs[i].Amount,
+			Reason:          reason,
+		})
+		if err != nil {
+			return "", err
+		}
+		writeOff := ProvenanceEvent{
+			EventType:          EventMaterialWrittenOff,
+			AgentID:            clientMSPID,
+			OnChainDataPayload: string(writeOffJSON),
+		}
+		err = s.appendAssetEvent(ctx, material, writeOff, "")
+		if err != nil {
+			return "", err
+		}
+		assetIDs = append(assetIDs, material.AssetID)
+	}
+	err = emitChaincodeEvent(ctx, EventScrap, assetIDs...)
+	if err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
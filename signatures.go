@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// signatureAlgorithm returns the SHA-256 signature algorithm matching the
+// public key of the certificate.
+func signatureAlgorithm(cert *x509.Certificate) (x509.SignatureAlgorithm, error) {
+	switch cert.PublicKeyAlgorithm {
+	case x509.ECDSA:
+		return x509.ECDSAWithSHA256, nil
+	case x509.RSA:
+		return x509.SHA256WithRSA, nil
+	case x509.Ed25519:
+		return x509.PureEd25519, nil
+	default:
+		return x509.UnknownSignatureAlgorithm, fmt.Errorf("%w: unsupported public key algorithm %s", ErrInvalidArgument, cert.PublicKeyAlgorithm)
+	}
+}
+
+// verifyPayloadSignature checks that signature, base64 encoded, is the
+// submitting identity's signature over payload: ECDSA or RSA over its SHA-256
+// digest, or Ed25519 over the payload itself.
+func verifyPayloadSignature(ctx contractapi.TransactionContextInterface, payload string, signature string) error {
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature must be base64 encoded: %v", ErrInvalidArgument, err)
+	}
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("failed to get client certificate: %w", err)
+	}
+	if cert == nil {
+		return fmt.Errorf("%w: the client identity has no X.509 certificate", ErrUnauthorized)
+	}
+	algorithm, err := signatureAlgorithm(cert)
+	if err != nil {
+		return err
+	}
+	err = cert.CheckSignature(algorithm, []byte(payload), signatureBytes)
+	if err != nil {
+		digest := sha256.Sum256([]byte(payload))
+		return fmt.Errorf("%w: the signature does not match payload digest %x for the submitting identity: %v", ErrUnauthorized, digest, err)
+	}
+	return nil
+}
+
+// AddSignedEvent records an event of the given type carrying onChainDataPayload
+// together with a detached signature of the payload by the submitting
+// identity. The signature is verified against the client certificate before
+// the event is written and is stored in the event, so it can be re-verified
+// later against the creator certificate of the recording transaction. It
+// returns the txID that recorded the event, or the one that already recorded
+// clientEventID for the asset.
+func (s *SmartContract) AddSignedEvent(ctx contractapi.TransactionContextInterface, assetID string, eventType string, onChainDataPayload string, offChainDataHash string, signature string, clientEventID string) (string, error) {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return "", err
+	}
+	if signature == "" {
+		return "", fmt.Errorf("%w: signature must not be empty", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	err = verifyPayloadSignature(ctx, onChainDataPayload, signature)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	event := ProvenanceEvent{
+		EventType:          typ,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: onChainDataPayload,
+		Signature:          signature,
+	}
+	return s.recordClientEvent(ctx, asset, event, "")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SplitChildSpec is one sub-batch requested from SplitAsset.
+type SplitChildSpec struct {
+	AssetID  string  `json:"assetID"`
+	Quantity float64 `json:"quantity"`
+}
+
+// SplitPayload is the on-chain payload of a SPLIT event.
+type SplitPayload struct {
+	ParentAssetID     string           `json:"parentAssetID"`
+	Children          []SplitChildSpec `json:"children"`
+	RemainingQuantity float64          `json:"remainingQuantity"`
+}
+
+// materialCertification returns the event that certified the asset's material:
+// its MATERIAL_CERTIFICATION, or the SPLIT that created a sub-batch.
+func materialCertification(events []ProvenanceEvent) (ProvenanceEvent, bool) {
+	for _, event := range events {
+		if event.EventType == EventMaterialCertification || event.EventType == EventSplit {
+			return event, true
+		}
+	}
+	return ProvenanceEvent{}, false
+}
+
+// SplitAsset divides a material batch into sub-batches described by
+// childSpecsJSON, a JSON array of SplitChildSpec. Each child is created as a
+// certified material inheriting the parent's material type, batch and
+// supplier as well as its shelf life, linked to the parent in the genealogy, and its quantity is deducted
+// from the parent's remaining quantity. SPLIT events are recorded on the parent
+// and every child. Only the owner of the parent may split it. It returns the
+// txID that recorded the split, or the one that already recorded clientEventID
+// for the parent.
+func (s *SmartContract) SplitAsset(ctx contractapi.TransactionContextInterface, parentID string, childSpecsJSON string, clientEventID string) (string, error) {
+	var specs []SplitChildSpec
+	err := json.Unmarshal([]byte(childSpecsJSON), &specs)
+	if err != nil {
+		return "", fmt.Errorf("%w: childSpecsJSON must be a JSON array of child specs: %v", ErrInvalidArgument, err)
+	}
+	if len(specs) == 0 {
+		return "", fmt.Errorf("%w: childSpecsJSON must contain at least one entry", ErrInvalidArgument)
+	}
+	parent, err := s.ReadAsset(ctx, parentID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwner(ctx, parent)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, parentID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	if parent.Quantity == 0 {
+		return "", fmt.Errorf("%w: material batch %s does not track a quantity", ErrInvalidState, parentID)
+	}
+	certification, ok := materialCertification(assetEvents(ctx, parent))
+	if !ok {
+		return "", fmt.Errorf("%w: the asset %s has no material certification to inherit", ErrInvalidState, parentID)
+	}
+
+	seen := make(map[string]bool)
+	total := 0.0
+	for i, spec := range specs {
+		err := validateAssetID(spec.AssetID)
+		if err != nil {
+			return "", fmt.Errorf("entry %d: %w", i, err)
+		}
+		if spec.Quantity <= 0 {
+			return "", fmt.Errorf("%w: entry %d: quantity must be positive, got %g", ErrInvalidArgument, i, spec.Quantity)
+		}
+		if spec.AssetID == parentID || seen[spec.AssetID] {
+			return "", fmt.Errorf("%w: entry %d: the asset %s appears more than once", ErrInvalidArgument, i, spec.AssetID)
+		}
+		seen[spec.AssetID] = true
+		exists, err := s.AssetExists(ctx, spec.AssetID)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return "", fmt.Errorf("%w: entry %d: the asset %s already exists", ErrAssetExists, i, spec.AssetID)
+		}
+		total += spec.Quantity
+	}
+	if total > parent.RemainingQuantity {
+		return "", fmt.Errorf("%w: material batch %s has %g remaining, %g requested", ErrInvalidState, parentID, parent.RemainingQuantity, total)
+	}
+
+	parent.RemainingQuantity -= total
+	payload, err := json.Marshal(SplitPayload{
+		ParentAssetID:     parentID,
+		Children:          specs,
+		RemainingQuantity: parent.RemainingQuantity,
+	})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventSplit,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+		MaterialType:       certification.MaterialType,
+		MaterialBatchID:    certification.MaterialBatchID,
+		SupplierID:         certification.SupplierID,
+		ExpiryTimestamp:    parent.ExpiryTimestamp,
+	}
+	assetIDs := []string{parentID}
+	for _, spec := range specs {
+		child := &Asset{
+			AssetID:           spec.AssetID,
+			Owner:             clientMSPID,
+			HistoryTxIDs:      []string{},
+			Quantity:          spec.Quantity,
+			RemainingQuantity: spec.Quantity,
+			ParentAssetIDs:    []string{parentID},
+			ExpiryTimestamp:   parent.ExpiryTimestamp,
+		}
+		err = s.appendAssetEvent(ctx, child, event, StageMaterialCertified)
+		if err != nil {
+			return "", err
+		}
+		parent.ChildAssetIDs = append(parent.ChildAssetIDs, spec.AssetID)
+		assetIDs = append(assetIDs, spec.AssetID)
+	}
+	err = s.appendAssetEvent(ctx, parent, event, "")
+	if err != nil {
+		return "", err
+	}
+	err = emitChaincodeEvent(ctx, event.EventType, assetIDs...)
+	if err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// lastEventTime returns the time of the asset's latest event. Assets written
+// before LastEventTimestamp was kept fall back to reading their last event.
+func lastEventTime(ctx contractapi.TransactionContextInterface, asset *Asset) (time.Time, error) {
+	timestamp := asset.LastEventTimestamp
+	if timestamp == "" && len(asset.HistoryTxIDs) > 0 {
+		eventJSON, err := readEventJSON(ctx, asset.HistoryTxIDs[len(asset.HistoryTxIDs)-1], asset.AssetID)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read from world state: %w", err)
+		}
+		var event ProvenanceEvent
+		if eventJSON != nil && json.Unmarshal(eventJSON, &event) == nil {
+			timestamp = event.Timestamp
+		}
+	}
+	return time.Parse(time.RFC3339, timestamp)
+}
+
+// GetStalledAssets returns the assets in the given lifecycle stage whose last
+// event is more than olderThanHours older than the current transaction time.
+// Assets whose last event time cannot be determined are left out.
+func (s *SmartContract) GetStalledAssets(ctx contractapi.TransactionContextInterface, stage string, olderThanHours int) ([]*Asset, error) {
+	if olderThanHours < 0 {
+		return nil, fmt.Errorf("%w: olderThanHours must not be negative, got %d", ErrInvalidArgument, olderThanHours)
+	}
+	assets, err := s.GetAssetsByStage(ctx, stage)
+	if err != nil {
+		return nil, err
+	}
+	now, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %w", err)
+	}
+	cutoff := now.AsTime().Add(-time.Duration(olderThanHours) * time.Hour)
+
+	stalled := []*Asset{}
+	for _, asset := range assets {
+		last, err := lastEventTime(ctx, asset)
+		if err != nil {
+			continue
+		}
+		if last.Before(cutoff) {
+			stalled = append(stalled, asset)
+		}
+	}
+	return stalled, nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The vendor tree carries no shimtest, so the tests run the contract against
+// the in-memory ledger below. Every transaction gets its own stub, with a
+// fresh txID and a timestamp one minute after the previous transaction's.
+
+const (
+	compositeKeyNamespace = "\x00"
+	maxUnicodeRune        = "\U0010FFFF"
+)
+
+// fakeLedger is the world state shared by the transactions of a test.
+type fakeLedger struct {
+	state      map[string][]byte
+	history    map[string][]*queryresult.KeyModification
+	private    map[string]map[string][]byte
+	validation map[string][]byte
+	txCount    int
+	now        time.Time
+}
+
+func newFakeLedger() *fakeLedger {
+	return &fakeLedger{
+		state:      make(map[string][]byte),
+		history:    make(map[string][]*queryresult.KeyModification),
+		private:    make(map[string]map[string][]byte),
+		validation: make(map[string][]byte),
+		now:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// context returns the context of a new transaction submitted by a client of
+// mspID holding the given certificate attributes.
+func (l *fakeLedger) context(function string, mspID string, attrs map[string]string) (*contractapi.TransactionContext, *fakeStub) {
+	l.txCount++
+	l.now = l.now.Add(time.Minute)
+	stub := &fakeStub{
+		ledger:    l,
+		txID:      fmt.Sprintf("tx%04d", l.txCount),
+		timestamp: l.now,
+		function:  function,
+	}
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&fakeIdentity{mspID: mspID, attrs: attrs})
+	return ctx, stub
+}
+
+// submit runs fn as a transaction and fails the test when it returns an error.
+func (l *fakeLedger) submit(t *testing.T, mspID string, attrs map[string]string, fn func(ctx contractapi.TransactionContextInterface) error) {
+	t.Helper()
+	ctx, _ := l.context("", mspID, attrs)
+	if err := fn(ctx); err != nil {
+		t.Fatalf("transaction %s failed: %v", ctx.GetStub().GetTxID(), err)
+	}
+}
+
+// sortedKeys returns the state keys in [startKey, endKey), an empty endKey
+// leaving the range open. Simple-key ranges skip composite keys as Fabric does.
+func (l *fakeLedger) sortedKeys(startKey string, endKey string) []string {
+	composite := strings.HasPrefix(startKey, compositeKeyNamespace)
+	var keys []string
+	for key := range l.state {
+		if strings.HasPrefix(key, compositeKeyNamespace) != composite {
+			continue
+		}
+		if key < startKey || (endKey != "" && key >= endKey) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (l *fakeLedger) iterator(keys []string) *fakeIterator {
+	kvs := make([]*queryresult.KV, len(keys))
+	for i, key := range keys {
+		kvs[i] = &queryresult.KV{Key: key, Value: l.state[key]}
+	}
+	return &fakeIterator{kvs: kvs}
+}
+
+// fakeStub implements the parts of the chaincode stub the contract uses. Any
+// other method panics through the nil embedded interface.
+type fakeStub struct {
+	shim.ChaincodeStubInterface
+	ledger    *fakeLedger
+	txID      string
+	timestamp time.Time
+	function  string
+	transient map[string][]byte
+	events    []string
+	writes    []string
+}
+
+func (s *fakeStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *fakeStub) GetChannelID() string {
+	return "test"
+}
+
+func (s *fakeStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.timestamp), nil
+}
+
+func (s *fakeStub) GetFunctionAndParameters() (string, []string) {
+	return s.function, nil
+}
+
+func (s *fakeStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *fakeStub) GetState(key string) ([]byte, error) {
+	return s.ledger.state[key], nil
+}
+
+func (s *fakeStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be empty")
+	}
+	s.writes = append(s.writes, "PutState "+key)
+	s.ledger.state[key] = value
+	s.ledger.history[key] = append(s.ledger.history[key], &queryresult.KeyModification{
+		TxId:      s.txID,
+		Value:     value,
+		Timestamp: timestamppb.New(s.timestamp),
+	})
+	return nil
+}
+
+func (s *fakeStub) DelState(key string) error {
+	s.writes = append(s.writes, "DelState "+key)
+	delete(s.ledger.state, key)
+	s.ledger.history[key] = append(s.ledger.history[key], &queryresult.KeyModification{
+		TxId:      s.txID,
+		Timestamp: timestamppb.New(s.timestamp),
+		IsDelete:  true,
+	})
+	return nil
+}
+
+func (s *fakeStub) SetEvent(name string, payload []byte) error {
+	s.events = append(s.events, name)
+	return nil
+}
+
+func (s *fakeStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.ledger.validation[key], nil
+}
+
+func (s *fakeStub) SetStateValidationParameter(key string, ep []byte) error {
+	s.writes = append(s.writes, "SetStateValidationParameter "+key)
+	s.ledger.validation[key] = ep
+	return nil
+}
+
+func (s *fakeStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.ledger.private[collection][key], nil
+}
+
+func (s *fakeStub) PutPrivateData(collection string, key string, value []byte) error {
+	s.writes = append(s.writes, "PutPrivateData "+key)
+	if s.ledger.private[collection] == nil {
+		s.ledger.private[collection] = make(map[string][]byte)
+	}
+	s.ledger.private[collection][key] = value
+	return nil
+}
+
+func (s *fakeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	key := compositeKeyNamespace + objectType + compositeKeyNamespace
+	for _, attribute := range attributes {
+		if strings.Contains(attribute, compositeKeyNamespace) {
+			return "", fmt.Errorf("attribute %q contains the composite key separator", attribute)
+		}
+		key += attribute + compositeKeyNamespace
+	}
+	return key, nil
+}
+
+func (s *fakeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.TrimPrefix(compositeKey, compositeKeyNamespace), compositeKeyNamespace)
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("invalid composite key %q", compositeKey)
+	}
+	return parts[0], parts[1 : len(parts)-1], nil
+}
+
+func (s *fakeStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	partialKey, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return s.ledger.iterator(s.ledger.sortedKeys(partialKey, partialKey+maxUnicodeRune)), nil
+}
+
+func (s *fakeStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return s.ledger.iterator(s.ledger.sortedKeys(startKey, endKey)), nil
+}
+
+func (s *fakeStub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	keys := s.ledger.sortedKeys(startKey, endKey)
+	next := ""
+	if pageSize > 0 && len(keys) > int(pageSize) {
+		next = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+	metadata := &peer.QueryResponseMetadata{FetchedRecordsCount: int32(len(keys)), Bookmark: next}
+	return s.ledger.iterator(keys), metadata, nil
+}
+
+// GetQueryResult returns no results: the fake ledger has no state database
+// to run rich queries against.
+func (s *fakeStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return &fakeIterator{}, nil
+}
+
+func (s *fakeStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return &fakeIterator{}, &peer.QueryResponseMetadata{}, nil
+}
+
+func (s *fakeStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &fakeHistoryIterator{modifications: s.ledger.history[key]}, nil
+}
+
+type fakeIterator struct {
+	kvs []*queryresult.KV
+	i   int
+}
+
+func (it *fakeIterator) HasNext() bool {
+	return it.i < len(it.kvs)
+}
+
+func (it *fakeIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("iterator exhausted")
+	}
+	it.i++
+	return it.kvs[it.i-1], nil
+}
+
+func (it *fakeIterator) Close() error {
+	return nil
+}
+
+type fakeHistoryIterator struct {
+	modifications []*queryresult.KeyModification
+	i             int
+}
+
+func (it *fakeHistoryIterator) HasNext() bool {
+	return it.i < len(it.modifications)
+}
+
+func (it *fakeHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("iterator exhausted")
+	}
+	it.i++
+	return it.modifications[it.i-1], nil
+}
+
+func (it *fakeHistoryIterator) Close() error {
+	return nil
+}
+
+// fakeIdentity is a client of an MSP holding certificate attributes.
+type fakeIdentity struct {
+	mspID string
+	attrs map[string]string
+}
+
+func (c *fakeIdentity) GetID() (string, error) {
+	return "x509::CN=user," + c.mspID, nil
+}
+
+func (c *fakeIdentity) GetMSPID() (string, error) {
+	return c.mspID, nil
+}
+
+func (c *fakeIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := c.attrs[attrName]
+	return value, found, nil
+}
+
+func (c *fakeIdentity) AssertAttributeValue(attrName string, attrValue string) error {
+	if c.attrs[attrName] != attrValue {
+		return fmt.Errorf("attribute %s is not %s", attrName, attrValue)
+	}
+	return nil
+}
+
+func (c *fakeIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, errors.New("the fake identity has no certificate")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// supplierIndex is the composite key object type linking a supplier to the
+// material batches it certified.
+const supplierIndex = "supplier~assetID"
+
+// indexSupplier records the supplier of a MATERIAL_CERTIFICATION event.
+func indexSupplier(ctx contractapi.TransactionContextInterface, assetID string, event ProvenanceEvent) error {
+	if event.EventType != EventMaterialCertification || event.SupplierID == "" {
+		return nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(supplierIndex, []string{event.SupplierID, assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create supplier index key: %w", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put supplier index: %w", err)
+	}
+	return nil
+}
+
+// SupplierSummary aggregates the quality record of a supplier's material.
+// FailureRate is FailedTests over TestedParts, 0 when no part was tested.
+type SupplierSummary struct {
+	SupplierID       string  `json:"supplierID"`
+	CertifiedBatches int     `json:"certifiedBatches"`
+	DescendantParts  int     `json:"descendantParts"`
+	TestedParts      int     `json:"testedParts"`
+	FailedTests      int     `json:"failedTests"`
+	FailureRate      float64 `json:"failureRate"`
+}
+
+// GetSupplierSummary counts the material batches certified for a supplier and,
+// walking the genealogy down from them, the descendant parts whose final test
+// failed. Batches are found through the supplier index, so batches certified
+// before the index existed are not counted.
+func (s *SmartContract) GetSupplierSummary(ctx contractapi.TransactionContextInterface, supplierID string) (*SupplierSummary, error) {
+	if supplierID == "" {
+		return nil, fmt.Errorf("%w: supplierID must not be empty", ErrInvalidArgument)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(supplierIndex, []string{supplierID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query supplier index: %w", err)
+	}
+	defer iterator.Close()
+
+	summary := &SupplierSummary{SupplierID: supplierID}
+	seen := make(map[string]bool)
+	var queue []string
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate supplier index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split supplier index key: %w", err)
+		}
+		summary.CertifiedBatches++
+		seen[keyParts[1]] = true
+		queue = append(queue, keyParts[1])
+	}
+
+	for len(queue) > 0 {
+		asset, err := s.ReadAsset(ctx, queue[0])
+		queue = queue[1:]
+		if errors.Is(err, ErrAssetNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, childID := range asset.ChildAssetIDs {
+			if seen[childID] {
+				continue
+			}
+			seen[childID] = true
+			summary.DescendantParts++
+			queue = append(queue, childID)
+		}
+		tested, failed := false, false
+		for _, event := range assetEvents(ctx, asset) {
+			if event.EventType == EventFinalTest {
+				tested = true
+				failed = failed || isFailingResult(event.FinalTestResult)
+			}
+		}
+		if tested {
+			summary.TestedParts++
+		}
+		if failed {
+			summary.FailedTests++
+		}
+	}
+	if summary.TestedParts > 0 {
+		summary.FailureRate = float64(summary.FailedTests) / float64(summary.TestedParts)
+	}
+	return summary, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// tagIndex is the composite key object type listing assets by tag key and value.
+const tagIndex = "tag~key~value~assetID"
+
+// maxTagKeyLength and maxTagValueLength bound the length of tag keys and values.
+const (
+	maxTagKeyLength   = 64
+	maxTagValueLength = 256
+)
+
+// validateTags rejects tag keys and values that are too long or contain
+// characters reserved by composite keys, and keys that CouchDB selectors would
+// read as nested fields or operators.
+func validateTags(tags map[string]string) error {
+	for key, value := range tags {
+		switch {
+		case key == "":
+			return fmt.Errorf("%w: tag keys must not be empty", ErrInvalidArgument)
+		case len(key) > maxTagKeyLength:
+			return fmt.Errorf("%w: tag key %q must be at most %d characters", ErrInvalidArgument, key, maxTagKeyLength)
+		case len(value) > maxTagValueLength:
+			return fmt.Errorf("%w: the value of tag %q must be at most %d characters", ErrInvalidArgument, key, maxTagValueLength)
+		case strings.ContainsAny(key, ".$"):
+			return fmt.Errorf("%w: tag key %q must not contain '.' or '$'", ErrInvalidArgument, key)
+		case strings.ContainsAny(key+value, "\x00"+string(utf8.MaxRune)):
+			return fmt.Errorf("%w: tag %q must not contain the null character or U+10FFFF", ErrInvalidArgument, key)
+		}
+	}
+	return nil
+}
+
+// updateTagIndex moves the asset's tag index entries from the before tags to
+// the after tags.
+func updateTagIndex(ctx contractapi.TransactionContextInterface, assetID string, before map[string]string, after map[string]string) error {
+	for key, value := range before {
+		if newValue, ok := after[key]; ok && newValue == value {
+			continue
+		}
+		indexKey, err := ctx.GetStub().CreateCompositeKey(tagIndex, []string{key, value, assetID})
+		if err != nil {
+			return fmt.Errorf("failed to create tag index key: %w", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete tag index: %w", err)
+		}
+	}
+	for key, value := range after {
+		if oldValue, ok := before[key]; ok && oldValue == value {
+			continue
+		}
+		indexKey, err := ctx.GetStub().CreateCompositeKey(tagIndex, []string{key, value, assetID})
+		if err != nil {
+			return fmt.Errorf("failed to create tag index key: %w", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put tag index: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetAssetTags replaces the tags of an asset with tagsJSON, a JSON object of
+// string values, and records a TAG event carrying the new tags. An empty
+// object removes every tag. Only the owner or the admin MSP may tag an asset.
+// It returns the txID that recorded the tags, or the one that already
+// recorded clientEventID for the asset.
+func (s *SmartContract) SetAssetTags(ctx contractapi.TransactionContextInterface, assetID string, tagsJSON string, clientEventID string) (string, error) {
+	var tags map[string]string
+	err := json.Unmarshal([]byte(tagsJSON), &tags)
+	if err != nil {
+		return "", fmt.Errorf("%w: tagsJSON must be a JSON object of string values: %v", ErrInvalidArgument, err)
+	}
+	err = validateTags(tags)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	clientMSPID, err := requireOwnerOrAdmin(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	payload, err := json.Marshal(tags)
+	if err != nil {
+		return "", err
+	}
+	err = updateTagIndex(ctx, assetID, asset.Tags, tags)
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventTag,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OnChainDataPayload: string(payload),
+	}
+	asset.Tags = tags
+	return s.recordClientEvent(ctx, asset, event, "")
+}
+
+// GetAssetsByTag returns the assets tagged with key set to value, or with key
+// set to any value when value is empty. It reads the tag index and works on
+// any state database.
+func (s *SmartContract) GetAssetsByTag(ctx contractapi.TransactionContextInterface, key string, value string) ([]*Asset, error) {
+	if key == "" {
+		return nil, fmt.Errorf("%w: key must not be empty", ErrInvalidArgument)
+	}
+	attributes := []string{key}
+	if value != "" {
+		attributes = append(attributes, value)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(tagIndex, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag index: %w", err)
+	}
+	defer iterator.Close()
+
+	assets := []*Asset{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate tag index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split tag index key: %w", err)
+		}
+		asset, err := s.ReadAsset(ctx, keyParts[2])
+		if errors.Is(err, ErrAssetNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// strictTimestampsConfig names the configuration entry that makes timestamp
+// regressions fail the transaction instead of only being reported.
+const strictTimestampsConfig = "strictTimestamps"
+
+// TimestampAnomaly is an event whose timestamp precedes the one before it in
+// the asset's history.
+type TimestampAnomaly struct {
+	TxID              string `json:"txID"`
+	Timestamp         string `json:"timestamp"`
+	PreviousTxID      string `json:"previousTxID"`
+	PreviousTimestamp string `json:"previousTimestamp"`
+}
+
+// timestampRegresses reports whether timestamp is earlier than previous. Both
+// are RFC3339 timestamps; unparsable values never count as a regression.
+func timestampRegresses(previous string, timestamp string) bool {
+	previousTime, err := time.Parse(time.RFC3339, previous)
+	if err != nil {
+		return false
+	}
+	currentTime, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return false
+	}
+	return currentTime.Before(previousTime)
+}
+
+// monotonicTimestampValidator rejects an event whose transaction timestamp is
+// earlier than the asset's last recorded event, when strict timestamps are
+// enabled. Transaction timestamps are set by the submitting client and
+// endorsed, not ordered, so a transaction endorsed earlier may commit after a
+// later one; regressions are therefore only reported unless configured strict.
+type monotonicTimestampValidator struct{}
+
+func (monotonicTimestampValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	history := pending.Asset.HistoryTxIDs
+	if len(history) == 0 {
+		return nil
+	}
+	value, err := getConfig(ctx, strictTimestampsConfig)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	strict, err := strconv.ParseBool(string(value))
+	if err != nil || !strict {
+		return err
+	}
+	lastTxID := history[len(history)-1]
+	eventJSON, err := readEventJSON(ctx, lastTxID, pending.Asset.AssetID)
+	if err != nil || eventJSON == nil {
+		return err
+	}
+	var last ProvenanceEvent
+	err = json.Unmarshal(eventJSON, &last)
+	if err != nil {
+		return err
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if timestampRegresses(last.Timestamp, timestamp) {
+		return fmt.Errorf("%w: transaction timestamp %s precedes the last event %s of asset %s at %s", ErrInvalidState, timestamp, lastTxID, pending.Asset.AssetID, last.Timestamp)
+	}
+	return nil
+}
+
+// SetStrictTimestamps configures whether events whose timestamp precedes the
+// asset's last event are rejected. Only the admin MSP may change it.
+func (s *SmartContract) SetStrictTimestamps(ctx contractapi.TransactionContextInterface, strict bool) error {
+	err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte(strconv.FormatBool(strict)), strictTimestampsConfig)
+}
+
+// GetTimestampAnomalies returns the events of an asset whose timestamp
+// precedes that of the event recorded before them.
+func (s *SmartContract) GetTimestampAnomalies(ctx contractapi.TransactionContextInterface, assetID string) ([]TimestampAnomaly, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	anomalies := []TimestampAnomaly{}
+	events := assetEvents(ctx, asset)
+	for i := 1; i < len(events); i++ {
+		if timestampRegresses(events[i-1].Timestamp, events[i].Timestamp) {
+			anomalies = append(anomalies, TimestampAnomaly{
+				TxID:              events[i].TxID,
+				Timestamp:         events[i].Timestamp,
+				PreviousTxID:      events[i-1].TxID,
+				PreviousTimestamp: events[i-1].Timestamp,
+			})
+		}
+	}
+	return anomalies, nil
+}
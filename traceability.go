@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// materialBatchIndex is the composite key object type linking a material batch
+// to the assets whose events reference it.
+const materialBatchIndex = "batch~assetID"
+
+// indexMaterialBatches records that the asset references every material batch
+// the event names, through MaterialBatchID or MaterialUsedID.
+func indexMaterialBatches(ctx contractapi.TransactionContextInterface, assetID string, event ProvenanceEvent) error {
+	for _, batchID := range materialBatchesOf([]ProvenanceEvent{event}) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(materialBatchIndex, []string{batchID, assetID})
+		if err != nil {
+			return fmt.Errorf("failed to create material batch index key: %w", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put material batch index: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetAssetsByMaterialBatch returns the IDs of the assets whose events reference
+// the material batch, such as parts printed from it.
+func (s *SmartContract) GetAssetsByMaterialBatch(ctx contractapi.TransactionContextInterface, materialBatchID string) ([]string, error) {
+	if materialBatchID == "" {
+		return nil, fmt.Errorf("%w: materialBatchID must not be empty", ErrInvalidArgument)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(materialBatchIndex, []string{materialBatchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query material batch index: %w", err)
+	}
+	defer iterator.Close()
+
+	assetIDs := []string{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate material batch index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split material batch index key: %w", err)
+		}
+		assetIDs = append(assetIDs, keyParts[1])
+	}
+	return assetIDs, nil
+}
+
+// createdIndex is the composite key object type listing assets by the
+// timestamp of their first event.
+const createdIndex = "created~timestamp~assetID"
+
+// indexCreation records the creation timestamp of an asset receiving its first event.
+func indexCreation(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	if len(asset.HistoryTxIDs) > 0 {
+		return nil
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	return putCreationIndex(ctx, asset.AssetID, timestamp)
+}
+
+// putCreationIndex records that the asset was created at timestamp.
+func putCreationIndex(ctx contractapi.TransactionContextInterface, assetID string, timestamp string) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(createdIndex, []string{timestamp, assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create creation index key: %w", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put creation index: %w", err)
+	}
+	return nil
+}
+
+// GetAssetsCreatedBetween returns the assets whose first event was recorded
+// between startTime and endTime inclusive. Both bounds are optional RFC3339
+// timestamps. It reads the creation index rather than every asset's events.
+func (s *SmartContract) GetAssetsCreatedBetween(ctx contractapi.TransactionContextInterface, startTime string, endTime string) ([]*Asset, error) {
+	start, err := parseTimeBound("startTime", startTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeBound("endTime", endTime)
+	if err != nil {
+		return nil, err
+	}
+	if start != nil && end != nil && start.After(*end) {
+		return nil, fmt.Errorf("%w: startTime %s is after endTime %s", ErrInvalidArgument, startTime, endTime)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(createdIndex, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query creation index: %w", err)
+	}
+	defer iterator.Close()
+
+	assets := []*Asset{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate creation index: %w", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split creation index key: %w", err)
+		}
+		created, err := time.Parse(time.RFC3339, keyParts[0])
+		if err != nil {
+			continue
+		}
+		if (start != nil && created.Before(*start)) || (end != nil && created.After(*end)) {
+			continue
+		}
+		asset, err := s.ReadAsset(ctx, keyParts[1])
+		if errors.Is(err, ErrAssetNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
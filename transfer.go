@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PendingTransfer is a transfer of an asset proposed by its owner From and
+// awaiting confirmation of receipt by To. ProposalID is the txID of the
+// proposal. KeepCustodian is set when a separately recorded custodian keeps
+// physical custody once the transfer is accepted.
+type PendingTransfer struct {
+	ProposalID    string `json:"proposalID"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	ProposedAt    string `json:"proposedAt"`
+	KeepCustodian bool   `json:"keepCustodian,omitempty" metadata:",optional"`
+}
+
+// TransferProposalPayload is the on-chain payload of the TRANSFER_PROPOSED and
+// TRANSFER_REJECTED events. Reason is set on rejection.
+type TransferProposalPayload struct {
+	ProposalID    string `json:"proposalID"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	KeepCustodian bool   `json:"keepCustodian,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// pendingTransfer returns the asset's pending transfer, or ErrNotFound when
+// none is pending.
+func pendingTransfer(asset *Asset) (*PendingTransfer, error) {
+	if asset.PendingTransfer == nil {
+		return nil, fmt.Errorf("%w: the asset %s has no pending transfer", ErrNotFound, asset.AssetID)
+	}
+	return asset.PendingTransfer, nil
+}
+
+// newPendingTransfer checks that the caller may propose handing the asset over
+// to newOwner and returns the proposal made by the current transaction: only
+// the current owner may propose a transfer, and an asset has at most one
+// pending transfer.
+func newPendingTransfer(ctx contractapi.TransactionContextInterface, asset *Asset, newOwner string, keepCustodian bool) (*PendingTransfer, error) {
+	_, err := requireOwner(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	if newOwner == asset.Owner {
+		return nil, fmt.Errorf("%w: the asset %s is already owned by %s", ErrInvalidState, asset.AssetID, newOwner)
+	}
+	if pending := asset.PendingTransfer; pending != nil {
+		return nil, fmt.Errorf("%w: the asset %s already has the pending transfer %s to %s", ErrInvalidState, asset.AssetID, pending.ProposalID, pending.To)
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &PendingTransfer{
+		ProposalID:    ctx.GetStub().GetTxID(),
+		From:          asset.Owner,
+		To:            newOwner,
+		ProposedAt:    timestamp,
+		KeepCustodian: keepCustodian,
+	}, nil
+}
+
+// ProposeTransfer proposes handing an asset over to newOwner and records a
+// TRANSFER_PROPOSED event. Ownership and physical custody only change once
+// newOwner confirms receipt through AcceptTransfer. It returns the proposal ID.
+func (s *SmartContract) ProposeTransfer(ctx contractapi.TransactionContextInterface, assetID string, newOwner string) (string, error) {
+	return s.proposeTransfer(ctx, assetID, newOwner, false)
+}
+
+// proposeTransfer records the TRANSFER_PROPOSED event of ProposeTransfer and
+// TransferOwnership.
+func (s *SmartContract) proposeTransfer(ctx contractapi.TransactionContextInterface, assetID string, newOwner string, keepCustodian bool) (string, error) {
+	if newOwner == "" {
+		return "", fmt.Errorf("%w: newOwner must not be empty", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	pending, err := newPendingTransfer(ctx, asset, newOwner, keepCustodian)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(TransferProposalPayload{
+		ProposalID:    pending.ProposalID,
+		From:          pending.From,
+		To:            pending.To,
+		KeepCustodian: pending.KeepCustodian,
+	})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventTransferProposed,
+		AgentID:            pending.From,
+		OnChainDataPayload: string(payload),
+	}
+	asset.PendingTransfer = pending
+	err = s.recordAssetEvent(ctx, asset, event, "")
+	if err != nil {
+		return "", err
+	}
+	return pending.ProposalID, nil
+}
+
+// AcceptTransfer confirms receipt of an asset whose transfer to the caller's
+// MSP is pending, records an OWNERSHIP_TRANSFER event and makes the caller the
+// owner and, unless a separate custodian keeps it, the holder of the asset.
+// Only the MSP the transfer was proposed to may accept it.
+func (s *SmartContract) AcceptTransfer(ctx contractapi.TransactionContextInterface, assetID string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	pending, err := pendingTransfer(asset)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	if clientMSPID != pending.To {
+		return fmt.Errorf("%w: the transfer of asset %s is proposed to %s, not %s", ErrUnauthorized, assetID, pending.To, clientMSPID)
+	}
+	transfer := OwnershipTransferPayload{
+		ProposalID:         pending.ProposalID,
+		PreviousOwner:      pending.From,
+		NewOwner:           pending.To,
+		CustodyTransferred: !pending.KeepCustodian,
+	}
+	if !pending.KeepCustodian {
+		transfer.PreviousCustodian = asset.Custodian
+	}
+	payload, err := json.Marshal(transfer)
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventOwnershipTransfer,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: string(payload),
+	}
+	setOwner(asset, pending.To)
+	if !pending.KeepCustodian {
+		asset.Custodian = ""
+	}
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
+
+// RejectTransfer declines the pending transfer of an asset, recording a
+// TRANSFER_REJECTED event with the reason; the asset stays with its owner.
+// The MSP the transfer was proposed to may reject it, and the owner may
+// withdraw it.
+func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterface, assetID string, reason string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	pending, err := pendingTransfer(asset)
+	if err != nil {
+		return err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	if clientMSPID != pending.To && clientMSPID != asset.Owner {
+		return fmt.Errorf("%w: client from %s is neither the owner nor the recipient of the transfer of asset %s", ErrUnauthorized, clientMSPID, assetID)
+	}
+	payload, err := json.Marshal(TransferProposalPayload{
+		ProposalID: pending.ProposalID,
+		From:       pending.From,
+		To:         pending.To,
+		Reason:     reason,
+	})
+	if err != nil {
+		return err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventTransferRejected,
+		AgentID:            clientMSPID,
+		OnChainDataPayload: string(payload),
+	}
+	asset.PendingTransfer = nil
+	return s.recordAssetEvent(ctx, asset, event, "")
+}
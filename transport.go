@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TransportPayload is the on-chain payload of a TRANSPORT event. Locations are
+// facility names or geolocations such as "48.8566,2.3522". Conditions holds the
+// shipping conditions as reported, such as temperature or humidity ranges.
+// ProposedOwner is set when the handoff proposed a transfer of ownership.
+type TransportPayload struct {
+	FromLocation  string          `json:"fromLocation"`
+	ToLocation    string          `json:"toLocation"`
+	CarrierID     string          `json:"carrierID"`
+	Conditions    json.RawMessage `json:"conditions,omitempty"`
+	ProposedOwner string          `json:"proposedOwner,omitempty"`
+}
+
+// RecordTransport records a TRANSPORT event logging the move of an asset from
+// fromLocation to toLocation by carrierID under the shipping conditions of
+// conditionsJSON, an optional JSON object. When newOwner is set, the handoff
+// also proposes transferring the asset to the receiving party, which only the
+// current owner may do. The transport's txID is then the proposal ID, and
+// ownership only changes once the receiver accepts through AcceptTransfer. It
+// returns the txID that recorded the transport, or the one that already
+// recorded clientEventID for the asset.
+func (s *SmartContract) RecordTransport(ctx contractapi.TransactionContextInterface, assetID string, fromLocation string, toLocation string, carrierID string, conditionsJSON string, offChainDataHash string, newOwner string, clientEventID string) (string, error) {
+	if strings.TrimSpace(fromLocation) == "" || strings.TrimSpace(toLocation) == "" {
+		return "", fmt.Errorf("%w: fromLocation and toLocation must not be empty", ErrInvalidArgument)
+	}
+	if carrierID == "" {
+		return "", fmt.Errorf("%w: carrierID must not be empty", ErrInvalidArgument)
+	}
+	payload := TransportPayload{
+		FromLocation: fromLocation,
+		ToLocation:   toLocation,
+		CarrierID:    carrierID,
+	}
+	if conditionsJSON != "" {
+		var conditions map[string]interface{}
+		err := json.Unmarshal([]byte(conditionsJSON), &conditions)
+		if err != nil {
+			return "", fmt.Errorf("%w: conditionsJSON must be a JSON object: %v", ErrInvalidArgument, err)
+		}
+		payload.Conditions = json.RawMessage(conditionsJSON)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	if newOwner != "" {
+		asset.PendingTransfer, err = newPendingTransfer(ctx, asset, newOwner, false)
+		if err != nil {
+			return "", err
+		}
+		payload.ProposedOwner = newOwner
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventTransport,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OffChainDataHash:   offChainDataHash,
+		OnChainDataPayload: string(payloadJSON),
+	}
+	return s.recordClientEvent(ctx, asset, event, "")
+}
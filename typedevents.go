@@ -0,0 +1,173 @@
+package main
+
+import "encoding/json"
+
+// EventEnvelope holds the fields every event carries whatever its type: the
+// event type and schema version, who recorded it and when, its off-chain
+// evidence and on-chain payload, and its link in the asset's hash chain.
+type EventEnvelope struct {
+	SchemaVersion      int       `json:"schemaVersion"`
+	EventType          EventType `json:"eventType"`
+	TxID               string    `json:"txID"`
+	AgentID            string    `json:"agentID"`
+	Timestamp          string    `json:"timestamp"`
+	OffChainDataHash   string    `json:"offChainDataHash,omitempty" metadata:",optional"`
+	HashAlgorithm      string    `json:"hashAlgorithm,omitempty" metadata:",optional"`
+	OnChainDataPayload string    `json:"onChainDataPayload,omitempty" metadata:",optional"`
+	ClientEventID      string    `json:"clientEventID,omitempty" metadata:",optional"`
+	Signature          string    `json:"signature,omitempty" metadata:",optional"`
+	PreviousEventHash  string    `json:"previousEventHash,omitempty" metadata:",optional"`
+}
+
+// MaterialCertificationEvent is a MATERIAL_CERTIFICATION or
+// MATERIAL_RECERTIFIED event.
+type MaterialCertificationEvent struct {
+	EventEnvelope
+	MaterialType    string `json:"materialType,omitempty" metadata:",optional"`
+	MaterialBatchID string `json:"materialBatchID,omitempty" metadata:",optional"`
+	SupplierID      string `json:"supplierID,omitempty" metadata:",optional"`
+	ExpiryTimestamp string `json:"expiryTimestamp,omitempty" metadata:",optional"`
+}
+
+// PrintJobEvent is a PRINT_JOB, PRINT_JOB_EVENTS, REPRINT, PRINTED or
+// MATERIAL_CONSUMED event.
+type PrintJobEvent struct {
+	EventEnvelope
+	PrintJobID     string `json:"printJobID,omitempty" metadata:",optional"`
+	MachineID      string `json:"machineID,omitempty" metadata:",optional"`
+	MaterialUsedID string `json:"materialUsedID,omitempty" metadata:",optional"`
+}
+
+// InspectionEvent is an INSPECTION or INSPECTED event.
+type InspectionEvent struct {
+	EventEnvelope
+	PrimaryInspectionResult string `json:"primaryInspectionResult,omitempty" metadata:",optional"`
+}
+
+// TestEvent is a FINAL_TEST or TESTED event.
+type TestEvent struct {
+	EventEnvelope
+	TestStandardApplied string `json:"testStandardApplied,omitempty" metadata:",optional"`
+	FinalTestResult     string `json:"finalTestResult,omitempty" metadata:",optional"`
+	CertificateID       string `json:"certificateID,omitempty" metadata:",optional"`
+}
+
+// CertificationEvent is a CERTIFIED, CERTIFICATION_PROPOSED or
+// CERTIFICATE_REVOKED event.
+type CertificationEvent struct {
+	EventEnvelope
+	TestStandardApplied string `json:"testStandardApplied,omitempty" metadata:",optional"`
+	CertificateID       string `json:"certificateID,omitempty" metadata:",optional"`
+}
+
+// InstallationEvent is an INSTALLED, ASSEMBLY or DISASSEMBLY event.
+type InstallationEvent struct {
+	EventEnvelope
+	ParentSerialNumber   string `json:"parentSerialNumber,omitempty" metadata:",optional"`
+	InstallationPosition string `json:"installationPosition,omitempty" metadata:",optional"`
+}
+
+// ServiceFailureEvent is a SERVICE_FAILURE event.
+type ServiceFailureEvent struct {
+	EventEnvelope
+	FailureMode  string `json:"failureMode,omitempty" metadata:",optional"`
+	ServiceHours int    `json:"serviceHours,omitempty" metadata:",optional"`
+}
+
+// plainEvent is a ProvenanceEvent without its typed JSON encoding, marshalled
+// with every field as declared.
+type plainEvent ProvenanceEvent
+
+// typedEvent returns the typed event of the event's type: one of the typed
+// event structs, or the bare EventEnvelope for event types carrying no fields
+// of their own.
+func typedEvent(event ProvenanceEvent) interface{} {
+	envelope := EventEnvelope{
+		SchemaVersion:      event.SchemaVersion,
+		EventType:          event.EventType,
+		TxID:               event.TxID,
+		AgentID:            event.AgentID,
+		Timestamp:          event.Timestamp,
+		OffChainDataHash:   event.OffChainDataHash,
+		HashAlgorithm:      event.HashAlgorithm,
+		OnChainDataPayload: event.OnChainDataPayload,
+		ClientEventID:      event.ClientEventID,
+		Signature:          event.Signature,
+		PreviousEventHash:  event.PreviousEventHash,
+	}
+	switch event.EventType {
+	case EventMaterialCertification, EventMaterialRecertified:
+		return MaterialCertificationEvent{
+			EventEnvelope:   envelope,
+			MaterialType:    event.MaterialType,
+			MaterialBatchID: event.MaterialBatchID,
+			SupplierID:      event.SupplierID,
+			ExpiryTimestamp: event.ExpiryTimestamp,
+		}
+	case EventPrintJob, EventPrintJobLegacy, EventPrintJobEvents, EventReprint, EventPrinted, EventMaterialConsumed:
+		return PrintJobEvent{
+			EventEnvelope:  envelope,
+			PrintJobID:     event.PrintJobID,
+			MachineID:      event.MachineID,
+			MaterialUsedID: event.MaterialUsedID,
+		}
+	case EventInspection, EventInspected:
+		return InspectionEvent{
+			EventEnvelope:           envelope,
+			PrimaryInspectionResult: event.PrimaryInspectionResult,
+		}
+	case EventFinalTest, EventTested:
+		return TestEvent{
+			EventEnvelope:       envelope,
+			TestStandardApplied: event.TestStandardApplied,
+			FinalTestResult:     event.FinalTestResult,
+			CertificateID:       event.CertificateID,
+		}
+	case EventCertified, EventCertificationProposed, EventCertificateRevoked:
+		return CertificationEvent{
+			EventEnvelope:       envelope,
+			TestStandardApplied: event.TestStandardApplied,
+			CertificateID:       event.CertificateID,
+		}
+	case EventInstalled, EventAssembly, EventDisassembly:
+		return InstallationEvent{
+			EventEnvelope:        envelope,
+			ParentSerialNumber:   event.ParentSerialNumber,
+			InstallationPosition: event.InstallationPosition,
+		}
+	case EventServiceFailure:
+		return ServiceFailureEvent{
+			EventEnvelope: envelope,
+			FailureMode:   event.FailureMode,
+			ServiceHours:  event.ServiceHours,
+		}
+	}
+	return envelope
+}
+
+// MarshalJSON encodes the event as the typed event of its event type, leaving
+// out the fields of other event types and empty optional fields, both on the
+// ledger and in query results. An event carrying a field its typed event does
+// not hold, such as one amended onto it, is encoded with all its non-empty
+// fields instead, so no data is lost. Events decode into ProvenanceEvent
+// whichever way they were encoded.
+func (event ProvenanceEvent) MarshalJSON() ([]byte, error) {
+	typedJSON, err := json.Marshal(typedEvent(event))
+	if err != nil {
+		return nil, err
+	}
+	var decoded ProvenanceEvent
+	if json.Unmarshal(typedJSON, &decoded) == nil && decoded == event {
+		return typedJSON, nil
+	}
+	fields, err := jsonFields(plainEvent(event))
+	if err != nil {
+		return nil, err
+	}
+	for field, value := range fields {
+		if isEmptyField(value) && !contractSetEventFields[field] {
+			delete(fields, field)
+		}
+	}
+	return json.Marshal(fields)
+}
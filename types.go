@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// EventType names the kind of a provenance event.
+type EventType string
+
+// The event types recorded by the chaincode. The stage-named event types are
+// the generic events AddHistoryEvent records when moving an asset to that stage.
+const (
+	EventMaterialCertification  EventType = "MATERIAL_CERTIFICATION"
+	EventMaterialQuantitySet    EventType = "MATERIAL_QUANTITY_SET"
+	EventMaterialConsumed       EventType = "MATERIAL_CONSUMED"
+	EventMaterialRecertified    EventType = "MATERIAL_RECERTIFIED"
+	EventMaterialWrittenOff     EventType = "MATERIAL_WRITTEN_OFF"
+	EventSplit                  EventType = "SPLIT"
+	EventPrintJob               EventType = "PRINT_JOB_COMPLETED"
+	EventPrintJobEvents         EventType = "PRINT_JOB_EVENTS"
+	EventPostProcessing         EventType = "POST_PROCESSING"
+	EventReprint                EventType = "REPRINT"
+	EventInspection             EventType = "INSPECTION"
+	EventFinalTest              EventType = "FINAL_TEST"
+	EventCertificationProposed  EventType = "CERTIFICATION_PROPOSED"
+	EventCertificationApproved  EventType = "CERTIFICATION_APPROVED"
+	EventCertificationWithdrawn EventType = "CERTIFICATION_WITHDRAWN"
+	EventAssembly               EventType = "ASSEMBLY"
+	EventDisassembly            EventType = "DISASSEMBLY"
+	EventServiceFailure         EventType = "SERVICE_FAILURE"
+	EventScrap                  EventType = "SCRAP"
+	EventCustodyTransfer        EventType = "CUSTODY_TRANSFER"
+	EventTransferProposed       EventType = "TRANSFER_PROPOSED"
+	EventOwnershipTransfer      EventType = "OWNERSHIP_TRANSFER"
+	EventTransferRejected       EventType = "TRANSFER_REJECTED"
+	EventTransport              EventType = "TRANSPORT"
+	EventMetadataUpdated        EventType = "METADATA_UPDATED"
+	EventPatch                  EventType = "PATCH"
+	EventTag                    EventType = "TAG"
+	EventHoldPlaced             EventType = "HOLD_PLACED"
+	EventHoldReleased           EventType = "HOLD_RELEASED"
+	EventRecalled               EventType = "RECALLED"
+	EventNCROpened              EventType = "NCR_OPENED"
+	EventNCRClosed              EventType = "NCR_CLOSED"
+	EventHashSuperseded         EventType = "HASH_SUPERSEDED"
+	EventAmendment              EventType = "AMENDMENT"
+	EventGenealogyLink          EventType = "GENEALOGY_LINK"
+	EventEndorsementPolicySet   EventType = "ENDORSEMENT_POLICY_SET"
+	EventArchivedSummary        EventType = "ARCHIVED_SUMMARY"
+	EventDelete                 EventType = "DELETE"
+	EventAssetImported          EventType = "ASSET_IMPORTED"
+
+	EventPrinted            EventType = EventType(StagePrinted)
+	EventPostProcessed      EventType = EventType(StagePostProcessed)
+	EventInspected          EventType = EventType(StageInspected)
+	EventTested             EventType = EventType(StageTested)
+	EventCertified          EventType = EventType(StageCertified)
+	EventInstalled          EventType = EventType(StageInstalled)
+	EventCertificateRevoked EventType = EventType(StageCertificateRevoked)
+)
+
+// LifecycleStage names a stage of the asset lifecycle state machine.
+type LifecycleStage string
+
+// The stages of the default lifecycle. Further stages may be added through
+// RegisterTransition.
+const (
+	StageMaterialCertified  LifecycleStage = "MATERIAL_CERTIFIED"
+	StagePrinted            LifecycleStage = "PRINTED"
+	StagePostProcessed      LifecycleStage = "POST_PROCESSED"
+	StageInspected          LifecycleStage = "INSPECTED"
+	StageTested             LifecycleStage = "TESTED"
+	StageRejected           LifecycleStage = "REJECTED"
+	StageCertified          LifecycleStage = "CERTIFIED"
+	StageInstalled          LifecycleStage = "INSTALLED"
+	StageDisassembled       LifecycleStage = "DISASSEMBLED"
+	StageCertificateRevoked LifecycleStage = "CERTIFICATE_REVOKED"
+	StageScrapped           LifecycleStage = "SCRAPPED"
+)
+
+// EventPrintJobLegacy is the type of the print jobs RecordPrintJob recorded
+// before they were named PRINT_JOB_COMPLETED. It is no longer recorded, but
+// events of this type already on the ledger are still read as print jobs.
+const EventPrintJobLegacy EventType = "PRINT_JOB"
+
+// knownEventTypes is the set of event types the chaincode records.
+var knownEventTypes = map[EventType]bool{
+	EventMaterialCertification:  true,
+	EventMaterialQuantitySet:    true,
+	EventMaterialConsumed:       true,
+	EventMaterialRecertified:    true,
+	EventMaterialWrittenOff:     true,
+	EventSplit:                  true,
+	EventPrintJob:               true,
+	EventPrintJobEvents:         true,
+	EventPostProcessing:         true,
+	EventReprint:                true,
+	EventInspection:             true,
+	EventFinalTest:              true,
+	EventCertificationProposed:  true,
+	EventCertificationApproved:  true,
+	EventCertificationWithdrawn: true,
+	EventAssembly:               true,
+	EventDisassembly:            true,
+	EventServiceFailure:         true,
+	EventScrap:                  true,
+	EventCustodyTransfer:        true,
+	EventTransferProposed:       true,
+	EventOwnershipTransfer:      true,
+	EventTransferRejected:       true,
+	EventTransport:              true,
+	EventMetadataUpdated:        true,
+	EventPatch:                  true,
+	EventTag:                    true,
+	EventHoldPlaced:             true,
+	EventHoldReleased:           true,
+	EventRecalled:               true,
+	EventNCROpened:              true,
+	EventNCRClosed:              true,
+	EventHashSuperseded:         true,
+	EventAmendment:              true,
+	EventGenealogyLink:          true,
+	EventEndorsementPolicySet:   true,
+	EventArchivedSummary:        true,
+	EventDelete:                 true,
+	EventAssetImported:          true,
+	EventPrinted:                true,
+	EventPostProcessed:          true,
+	EventInspected:              true,
+	EventTested:                 true,
+	EventCertified:              true,
+	EventInstalled:              true,
+	EventCertificateRevoked:     true,
+}
+
+// parseLifecycleStage converts a stage passed to a transaction, rejecting any
+// stage that neither the default lifecycle nor a registered transition knows.
+func parseLifecycleStage(ctx contractapi.TransactionContextInterface, value string) (LifecycleStage, error) {
+	if value == "" {
+		return "", fmt.Errorf("%w: stage must not be empty", ErrInvalidArgument)
+	}
+	stage := LifecycleStage(value)
+	known, err := isKnownStage(ctx, stage)
+	if err != nil {
+		return "", err
+	}
+	if !known {
+		return "", fmt.Errorf("%w: unknown lifecycle stage %s", ErrInvalidArgument, value)
+	}
+	return stage, nil
+}
+
+// parseEventType converts an event type passed to a transaction, rejecting any
+// type outside the known set. Since AddHistoryEvent records events named after
+// stages, the names of registered stages are accepted too.
+func parseEventType(ctx contractapi.TransactionContextInterface, value string) (EventType, error) {
+	if value == "" {
+		return "", fmt.Errorf("%w: eventType must not be empty", ErrInvalidArgument)
+	}
+	eventType := EventType(value)
+	if knownEventTypes[eventType] {
+		return eventType, nil
+	}
+	known, err := isKnownStage(ctx, LifecycleStage(value))
+	if err != nil {
+		return "", err
+	}
+	if !known {
+		return "", fmt.Errorf("%w: unknown event type %s", ErrInvalidArgument, value)
+	}
+	return eventType, nil
+}
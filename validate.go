@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// EventParams are the arguments of the event submitted to ValidateEvent, named
+// as in ProvenanceEvent. Each event type reads the arguments of the
+// transaction that records it and ignores the others.
+type EventParams struct {
+	OffChainDataHash        string        `json:"offChainDataHash"`
+	HashAlgorithm           string        `json:"hashAlgorithm"`
+	ClientEventID           string        `json:"clientEventID"`
+	MaterialType            string        `json:"materialType"`
+	MaterialBatchID         string        `json:"materialBatchID"`
+	SupplierID              string        `json:"supplierID"`
+	ExpiryTimestamp         string        `json:"expiryTimestamp"`
+	PrintJobID              string        `json:"printJobID"`
+	MachineID               string        `json:"machineID"`
+	MaterialUsedID          string        `json:"materialUsedID"`
+	MaterialAmount          float64       `json:"materialAmount"`
+	BuildFileHash           string        `json:"buildFileHash"`
+	ParametersHash          string        `json:"parametersHash"`
+	PrimaryInspectionResult string        `json:"primaryInspectionResult"`
+	TestStandardApplied     string        `json:"testStandardApplied"`
+	FinalTestResult         string        `json:"finalTestResult"`
+	Measurements            []Measurement `json:"measurements"`
+	CertificateID           string        `json:"certificateID"`
+	ParentSerialNumber      string        `json:"parentSerialNumber"`
+	InstallationPosition    string        `json:"installationPosition"`
+}
+
+// EventValidation is the result of ValidateEvent. Reasons lists every check
+// the event fails, each prefixed with its error code; Accepted is true when
+// there are none. PriorTxID is set when the clientEventID was already recorded,
+// in which case submitting the event returns that transaction and records
+// nothing.
+type EventValidation struct {
+	AssetID   string         `json:"assetID"`
+	EventType EventType      `json:"eventType"`
+	NextStage LifecycleStage `json:"nextStage"`
+	Accepted  bool           `json:"accepted"`
+	Reasons   []string       `json:"reasons"`
+	PriorTxID string         `json:"priorTxID,omitempty" metadata:",optional"`
+}
+
+// isRejection reports whether err rejects the event, as opposed to a failure
+// to read the ledger.
+func isRejection(err error) bool {
+	for _, sentinel := range []error{ErrAssetNotFound, ErrAssetExists, ErrNotFound, ErrUnauthorized, ErrInvalidArgument, ErrInvalidState} {
+		if errors.Is(err, sentinel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateEvent reports whether the event of eventType described by
+// paramsJSON, a JSON object of EventParams, would be accepted against the
+// asset, without recording it. MATERIAL_CERTIFICATION is checked as
+// CreateMaterialCertification, PRINT_JOB as RecordPrintJob, INSPECTION as
+// RecordInspection, FINAL_TEST as RecordFinalTest and INSTALLED as
+// RecordInstallation; any other lifecycle stage is checked as AddHistoryEvent.
+// Argument checks run first; when they pass, every validator of the chain runs
+// and all of their rejections are reported rather than only the first.
+// Transactions with side effects on other assets, such as material
+// consumption, are checked for their preconditions only.
+func (s *SmartContract) ValidateEvent(ctx contractapi.TransactionContextInterface, assetID string, eventType string, paramsJSON string) (*EventValidation, error) {
+	parsedType, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	var params EventParams
+	if paramsJSON != "" {
+		err = json.Unmarshal([]byte(paramsJSON), &params)
+		if err != nil {
+			return nil, fmt.Errorf("%w: paramsJSON must be a JSON object of event parameters: %v", ErrInvalidArgument, err)
+		}
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	result := &EventValidation{AssetID: assetID, EventType: parsedType, Reasons: []string{}}
+	// reject records err as a reason, returning false when it is not a rejection.
+	reject := func(err error) bool {
+		if !isRejection(err) {
+			return false
+		}
+		result.Reasons = append(result.Reasons, err.Error())
+		return true
+	}
+
+	event := ProvenanceEvent{
+		EventType:        parsedType,
+		AgentID:          clientMSPID,
+		ClientEventID:    params.ClientEventID,
+		OffChainDataHash: params.OffChainDataHash,
+		HashAlgorithm:    params.HashAlgorithm,
+	}
+	var asset *Asset
+	var argumentErr error
+	generic := false
+	switch parsedType {
+	case EventMaterialCertification:
+		event.ClientEventID = ""
+		event.MaterialType = params.MaterialType
+		event.MaterialBatchID = params.MaterialBatchID
+		event.SupplierID = params.SupplierID
+		result.NextStage = StageMaterialCertified
+		argumentErr = validateAssetID(assetID)
+		if argumentErr == nil {
+			event.ExpiryTimestamp, argumentErr = parseExpiry("expiryTimestamp", params.ExpiryTimestamp)
+		}
+		if argumentErr == nil {
+			exists, err := s.AssetExists(ctx, assetID)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				argumentErr = fmt.Errorf("%w: the asset %s already exists", ErrAssetExists, assetID)
+			}
+		}
+		asset = &Asset{
+			AssetID:         assetID,
+			Owner:           clientMSPID,
+			HistoryTxIDs:    []string{},
+			ExpiryTimestamp: event.ExpiryTimestamp,
+		}
+	case EventPrintJob:
+		event.PrintJobID = params.PrintJobID
+		event.MachineID = params.MachineID
+		event.MaterialUsedID = params.MaterialUsedID
+		result.NextStage = StagePrinted
+		argumentErr = checkPrintJobArguments(assetID, params.PrintJobID, params.MachineID, params.MaterialUsedID, params.MaterialAmount, params.BuildFileHash, params.ParametersHash)
+	case EventInspection:
+		result.NextStage = StageInspected
+		var inspectionResult QualityResult
+		inspectionResult, argumentErr = checkQualityResult("primaryInspectionResult", params.PrimaryInspectionResult, params.Measurements)
+		event.PrimaryInspectionResult = string(inspectionResult)
+	case EventFinalTest:
+		event.TestStandardApplied = params.TestStandardApplied
+		event.CertificateID = params.CertificateID
+		var testResult QualityResult
+		testResult, argumentErr = checkFinalTestArguments(params.TestStandardApplied, params.FinalTestResult, params.CertificateID, params.Measurements)
+		event.FinalTestResult = string(testResult)
+		result.NextStage = finalTestStage(params.FinalTestResult)
+	case EventInstalled:
+		event.ParentSerialNumber = params.ParentSerialNumber
+		event.InstallationPosition = params.InstallationPosition
+		result.NextStage = StageInstalled
+		if params.ParentSerialNumber == "" {
+			argumentErr = fmt.Errorf("%w: parentSerialNumber must not be empty", ErrInvalidArgument)
+		}
+	default:
+		stage := LifecycleStage(parsedType)
+		known, err := isKnownStage(ctx, stage)
+		if err != nil {
+			return nil, err
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: events of type %s cannot be validated", ErrInvalidArgument, parsedType)
+		}
+		result.NextStage = stage
+		generic = true
+	}
+	if argumentErr != nil {
+		if !reject(argumentErr) {
+			return nil, argumentErr
+		}
+		return result, nil
+	}
+
+	if asset == nil {
+		asset, err = s.ReadAsset(ctx, assetID)
+		if err != nil {
+			if !reject(err) {
+				return nil, err
+			}
+			return result, nil
+		}
+		result.PriorTxID, err = priorClientEvent(ctx, assetID, event.ClientEventID)
+		if err != nil {
+			return nil, err
+		}
+		if result.PriorTxID != "" {
+			result.Accepted = true
+			return result, nil
+		}
+	}
+	if parsedType == EventInstalled || generic {
+		_, err = requireOwner(ctx, asset)
+		if err != nil && !reject(err) {
+			return nil, err
+		}
+	}
+	if parsedType == EventInstalled && asset.InstalledIn != "" {
+		reject(fmt.Errorf("%w: the asset %s is already installed in %s", ErrInvalidState, assetID, asset.InstalledIn))
+	}
+	if parsedType == EventPrintJob {
+		err = s.checkPrintMaterial(ctx, params.MaterialUsedID, params.MaterialAmount)
+		if err != nil && !reject(err) {
+			return nil, err
+		}
+	}
+	pending := &PendingEvent{Asset: asset, Event: &event, NextStage: result.NextStage}
+	for _, validator := range validators {
+		err = validator.Validate(ctx, pending)
+		if err != nil && !reject(err) {
+			return nil, err
+		}
+	}
+	result.Accepted = len(result.Reasons) == 0
+	return result, nil
+}
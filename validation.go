@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PendingEvent is an event about to be recorded against an asset. Asset still
+// carries its current lifecycle stage and NextStage is the stage it moves to,
+// empty when the stage does not change.
+type PendingEvent struct {
+	Asset     *Asset
+	Event     *ProvenanceEvent
+	NextStage LifecycleStage
+}
+
+// Validator checks a pending event before anything is written to the ledger.
+type Validator interface {
+	Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error
+}
+
+// validators is the ordered chain every event write passes through. New rules
+// are added as their own Validator type and registered here.
+var validators = []Validator{
+	roleValidator{},
+	attributeValidator{},
+	terminalStageValidator{},
+	holdValidator{},
+	lifecycleValidator{},
+	monotonicTimestampValidator{},
+	offChainHashValidator{},
+	offChainHashFormatValidator{},
+	requiredFieldsValidator{},
+	payloadSchemaValidator{},
+	revokedCertificateValidator{},
+	uniqueCertificateValidator{},
+	certificationValidator{},
+	certificationApprovalValidator{},
+}
+
+// runValidators runs the validator chain in order and stops at the first failure.
+func runValidators(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	for _, validator := range validators {
+		if err := validator.Validate(ctx, pending); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxAssetIDLength bounds the length of asset IDs.
+const maxAssetIDLength = 128
+
+// validateAssetID rejects asset IDs that are empty, padded with whitespace,
+// too long, or that would collide with event or composite keys.
+func validateAssetID(assetID string) error {
+	switch {
+	case strings.TrimSpace(assetID) == "":
+		return fmt.Errorf("%w: assetID must not be empty", ErrInvalidArgument)
+	case strings.TrimSpace(assetID) != assetID:
+		return fmt.Errorf("%w: assetID %q must not start or end with whitespace", ErrInvalidArgument, assetID)
+	case len(assetID) > maxAssetIDLength:
+		return fmt.Errorf("%w: assetID must be at most %d characters, got %d", ErrInvalidArgument, maxAssetIDLength, len(assetID))
+	case strings.HasPrefix(assetID, "EVENT_"):
+		return fmt.Errorf("%w: assetID %q must not start with the reserved prefix EVENT_", ErrInvalidArgument, assetID)
+	case strings.ContainsRune(assetID, 0):
+		return fmt.Errorf("%w: assetID %q must not contain the null character", ErrInvalidArgument, assetID)
+	}
+	return nil
+}
+
+// offChainHashConfig names the configuration entries overriding whether an
+// event type must carry an off-chain data hash.
+const offChainHashConfig = "offChainHashRequired"
+
+// offChainHashRequired lists the event types that must carry off-chain
+// evidence unless reconfigured. Any other event type may omit the hash.
+var offChainHashRequired = map[EventType]bool{
+	EventMaterialCertification: true,
+	EventMaterialRecertified:   true,
+	EventPrinted:               true,
+	EventPrintJob:              true,
+	EventPostProcessing:        true,
+	EventPostProcessed:         true,
+	EventReprint:               true,
+	EventInspected:             true,
+	EventInspection:            true,
+	EventTested:                true,
+	EventFinalTest:             true,
+	EventInstalled:             true,
+	EventServiceFailure:        true,
+}
+
+// isOffChainHashRequired reports whether events of eventType need an off-chain
+// data hash, honouring any override stored in the ledger configuration.
+func isOffChainHashRequired(ctx contractapi.TransactionContextInterface, eventType EventType) (bool, error) {
+	value, err := getConfig(ctx, offChainHashConfig, string(eventType))
+	if err != nil {
+		return false, err
+	}
+	if value == nil {
+		return offChainHashRequired[eventType], nil
+	}
+	return strconv.ParseBool(string(value))
+}
+
+// offChainHashValidator rejects an event without an off-chain data hash when its type requires one.
+type offChainHashValidator struct{}
+
+func (offChainHashValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if pending.Event.OffChainDataHash != "" {
+		return nil
+	}
+	required, err := isOffChainHashRequired(ctx, pending.Event.EventType)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fmt.Errorf("%w: events of type %s require an offChainDataHash", ErrInvalidArgument, pending.Event.EventType)
+	}
+	return nil
+}
+
+// defaultHashAlgorithm is the algorithm assumed for an off-chain data hash
+// given without one.
+const defaultHashAlgorithm = "sha256"
+
+// hashAlgorithmLengths maps the supported off-chain hash algorithms to the
+// length of their hex digest.
+var hashAlgorithmLengths = map[string]int{
+	"sha256":   64,
+	"sha512":   128,
+	"sha3-256": 64,
+	"sha3-512": 128,
+}
+
+// resolveOffChainHash splits hash into its algorithm and lowercase hex digest
+// and checks the digest length matches the algorithm. The algorithm comes from
+// hashAlgorithm or from a prefix as in "sha512:<digest>"; when both are given
+// they must agree, and when neither is it defaults to sha256.
+func resolveOffChainHash(hashAlgorithm string, hash string) (string, string, error) {
+	algorithm, digest := hashAlgorithm, hash
+	if i := strings.Index(hash, ":"); i >= 0 {
+		if hashAlgorithm != "" && hashAlgorithm != hash[:i] {
+			return "", "", fmt.Errorf("%w: invalid offChainDataHash %q: prefix does not match hash algorithm %s", ErrInvalidArgument, hash, hashAlgorithm)
+		}
+		algorithm, digest = hash[:i], hash[i+1:]
+	}
+	if algorithm == "" {
+		algorithm = defaultHashAlgorithm
+	}
+	length, ok := hashAlgorithmLengths[algorithm]
+	if !ok {
+		return "", "", fmt.Errorf("%w: invalid offChainDataHash %q: unsupported hash algorithm %q", ErrInvalidArgument, hash, algorithm)
+	}
+	if len(digest) != length {
+		return "", "", fmt.Errorf("%w: invalid offChainDataHash %q: a %s digest must be %d hex characters, got %d", ErrInvalidArgument, hash, algorithm, length, len(digest))
+	}
+	if _, err := hex.DecodeString(digest); err != nil || strings.ToLower(digest) != digest {
+		return "", "", fmt.Errorf("%w: invalid offChainDataHash %q: digest must be lowercase hex", ErrInvalidArgument, hash)
+	}
+	return algorithm, digest, nil
+}
+
+// offChainHashFormatValidator rejects an off-chain data hash that is not a well-formed digest.
+type offChainHashFormatValidator struct{}
+
+func (offChainHashFormatValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	if pending.Event.OffChainDataHash == "" {
+		return nil
+	}
+	_, _, err := resolveOffChainHash(pending.Event.HashAlgorithm, pending.Event.OffChainDataHash)
+	return err
+}
+
+// SetOffChainHashRequirement configures whether events of eventType must carry
+// an off-chain data hash. Only the admin MSP may change it.
+func (s *SmartContract) SetOffChainHashRequirement(ctx contractapi.TransactionContextInterface, eventType string, required bool) error {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	err = requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return putConfig(ctx, []byte(strconv.FormatBool(required)), offChainHashConfig, string(typ))
+}
+
+// GetOffChainHashRequirement reports whether events of eventType must carry an off-chain data hash.
+func (s *SmartContract) GetOffChainHashRequirement(ctx contractapi.TransactionContextInterface, eventType string) (bool, error) {
+	typ, err := parseEventType(ctx, eventType)
+	if err != nil {
+		return false, err
+	}
+	return isOffChainHashRequired(ctx, typ)
+}
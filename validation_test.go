@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestOffChainHashValidator(t *testing.T) {
+	required, optional := true, false
+	tests := []struct {
+		name      string
+		eventType EventType
+		hash      string
+		override  *bool
+		wantErr   error
+	}{
+		{name: "mandatory type without hash", eventType: EventPrintJob, wantErr: ErrInvalidArgument},
+		{name: "mandatory type with hash", eventType: EventPrintJob, hash: testHash},
+		{name: "optional type without hash", eventType: EventTransport},
+		{name: "optional type with hash", eventType: EventTransport, hash: testHash},
+		{name: "mandatory type made optional", eventType: EventInspection, override: &optional},
+		{name: "optional type made mandatory", eventType: EventScrap, override: &required, wantErr: ErrInvalidArgument},
+	}
+	s := new(SmartContract)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newFakeLedger()
+			l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+				return s.InitLedger(ctx, testAdminMSP)
+			})
+			if tt.override != nil {
+				l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+					return s.SetOffChainHashRequirement(ctx, string(tt.eventType), *tt.override)
+				})
+			}
+			ctx, _ := l.context("", testSupplierMSP, nil)
+			pending := &PendingEvent{
+				Asset: &Asset{AssetID: "PART-1", Owner: testSupplierMSP},
+				Event: &ProvenanceEvent{EventType: tt.eventType, OffChainDataHash: tt.hash},
+			}
+			err := offChainHashValidator{}.Validate(ctx, pending)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Validate() = %v, want no error", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecordEventRequiresOffChainHash(t *testing.T) {
+	s := new(SmartContract)
+	tests := []struct {
+		name    string
+		attrs   map[string]string
+		record  func(ctx contractapi.TransactionContextInterface) error
+		wantErr bool
+	}{
+		{
+			name:  "print job without hash",
+			attrs: operator,
+			record: func(ctx contractapi.TransactionContextInterface) error {
+				_, err := s.RecordPrintJob(ctx, "PART-1", "JOB-1", "MACHINE-1", "MAT-1", 0, "", "", "", "")
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "transport without hash",
+			record: func(ctx contractapi.TransactionContextInterface) error {
+				_, err := s.RecordTransport(ctx, "PART-1", "Hamburg", "Toulouse", "CARRIER-1", "", "", "", "")
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newFakeLedger()
+			l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+				return s.InitLedger(ctx, testAdminMSP)
+			})
+			for _, assetID := range []string{"MAT-1", "PART-1"} {
+				l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+					return s.CreateMaterialCertification(ctx, assetID, "Ti-6Al-4V", "BATCH-1", "SUP-1", testHash, "", "")
+				})
+			}
+			ctx, stub := l.context("", testSupplierMSP, tt.attrs)
+			err := tt.record(ctx)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidArgument) {
+					t.Fatalf("recording without a hash = %v, want %v", err, ErrInvalidArgument)
+				}
+				if len(stub.writes) != 0 {
+					t.Fatalf("rejected event wrote %v", stub.writes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("recording without a hash = %v, want no error", err)
+			}
+		})
+	}
+}
+
+// recordingValidator notes its name in calls and fails with err when set.
+type recordingValidator struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (v recordingValidator) Validate(ctx contractapi.TransactionContextInterface, pending *PendingEvent) error {
+	*v.calls = append(*v.calls, v.name)
+	return v.err
+}
+
+func TestRunValidatorsStopsAtFirstFailure(t *testing.T) {
+	errFirst := errors.New("first failed")
+	errSecond := errors.New("second failed")
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls []string
+		wantErr   error
+	}{
+		{name: "all pass", errs: []error{nil, nil, nil}, wantCalls: []string{"v0", "v1", "v2"}},
+		{name: "first fails", errs: []error{errFirst, nil, nil}, wantCalls: []string{"v0"}, wantErr: errFirst},
+		{name: "middle fails", errs: []error{nil, errSecond, nil}, wantCalls: []string{"v0", "v1"}, wantErr: errSecond},
+		{name: "two fail", errs: []error{nil, errSecond, errFirst}, wantCalls: []string{"v0", "v1"}, wantErr: errSecond},
+		{name: "last fails", errs: []error{nil, nil, errFirst}, wantCalls: []string{"v0", "v1", "v2"}, wantErr: errFirst},
+	}
+	original := validators
+	t.Cleanup(func() { validators = original })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			validators = nil
+			for i, err := range tt.errs {
+				validators = append(validators, recordingValidator{name: fmt.Sprintf("v%d", i), err: err, calls: &calls})
+			}
+			ctx, _ := newFakeLedger().context("", testSupplierMSP, nil)
+			err := runValidators(ctx, &PendingEvent{Asset: &Asset{}, Event: &ProvenanceEvent{}})
+			if err != tt.wantErr {
+				t.Fatalf("runValidators() = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Fatalf("validators ran %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// TestValidatorChainOrder checks that the registered chain reports the
+// earlier of two failing rules: the attribute check runs before the
+// off-chain hash check.
+func TestValidatorChainOrder(t *testing.T) {
+	s := new(SmartContract)
+	tests := []struct {
+		name    string
+		attrs   map[string]string
+		wantErr error
+	}{
+		{name: "missing attribute and hash", wantErr: ErrUnauthorized},
+		{name: "missing hash only", attrs: operator, wantErr: ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newFakeLedger()
+			l.submit(t, testAdminMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+				return s.InitLedger(ctx, testAdminMSP)
+			})
+			for _, assetID := range []string{"MAT-1", "PART-1"} {
+				l.submit(t, testSupplierMSP, nil, func(ctx contractapi.TransactionContextInterface) error {
+					return s.CreateMaterialCertification(ctx, assetID, "Ti-6Al-4V", "BATCH-1", "SUP-1", testHash, "", "")
+				})
+			}
+			ctx, _ := l.context("", testSupplierMSP, tt.attrs)
+			_, err := s.RecordPrintJob(ctx, "PART-1", "JOB-1", "MACHINE-1", "MAT-1", 0, "", "", "", "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RecordPrintJob() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
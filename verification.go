@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// OffChainVerification is the result of checking off-chain data against the
+// hash committed on-chain. When the hash has been superseded, CurrentHash and
+// SupersededByTxID point to the latest correction.
+type OffChainVerification struct {
+	Match            bool   `json:"match"`
+	HashAlgorithm    string `json:"hashAlgorithm"`
+	ExpectedHash     string `json:"expectedHash"`
+	Superseded       bool   `json:"superseded"`
+	SupersededByTxID string `json:"supersededByTxID"`
+	CurrentHash      string `json:"currentHash"`
+}
+
+// HashSupersessionPayload is the on-chain payload of a HASH_SUPERSEDED event.
+type HashSupersessionPayload struct {
+	PreviousTxID string `json:"previousTxID"`
+	PreviousHash string `json:"previousHash"`
+	Reason       string `json:"reason"`
+}
+
+// historyEvent returns the event of txID in the asset's history.
+func historyEvent(ctx contractapi.TransactionContextInterface, asset *Asset, txID string) (*ProvenanceEvent, error) {
+	inHistory := false
+	for _, historyTxID := range asset.HistoryTxIDs {
+		if historyTxID == txID {
+			inHistory = true
+			break
+		}
+	}
+	if !inHistory {
+		return nil, fmt.Errorf("%w: the transaction %s is not part of the history of asset %s", ErrInvalidArgument, txID, asset.AssetID)
+	}
+	eventJSON, err := readEventJSON(ctx, txID, asset.AssetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if eventJSON == nil {
+		return nil, fmt.Errorf("%w: the event %s does not exist", ErrNotFound, txID)
+	}
+	var event ProvenanceEvent
+	err = json.Unmarshal(eventJSON, &event)
+	if err != nil {
+		return nil, err
+	}
+	event.TxID = txID
+	return &event, nil
+}
+
+// committedEvent returns the event of txID in the asset's history that
+// committed an off-chain data hash.
+func committedEvent(ctx contractapi.TransactionContextInterface, asset *Asset, txID string) (*ProvenanceEvent, error) {
+	event, err := historyEvent(ctx, asset, txID)
+	if err != nil {
+		return nil, err
+	}
+	if event.OffChainDataHash == "" {
+		return nil, fmt.Errorf("%w: the event %s did not commit an off-chain data hash", ErrInvalidState, txID)
+	}
+	return event, nil
+}
+
+// supersessions maps each txID of the asset's history whose hash was
+// superseded to the HASH_SUPERSEDED event that replaced it.
+func supersessions(events []ProvenanceEvent) map[string]ProvenanceEvent {
+	superseded := make(map[string]ProvenanceEvent)
+	for _, event := range events {
+		if event.EventType != EventHashSuperseded {
+			continue
+		}
+		var payload HashSupersessionPayload
+		if json.Unmarshal([]byte(event.OnChainDataPayload), &payload) != nil {
+			continue
+		}
+		superseded[payload.PreviousTxID] = event
+	}
+	return superseded
+}
+
+// VerifyOffChainData compares providedHash with the off-chain data hash the
+// event of txID committed for the asset, and reports whether that hash has
+// since been superseded. providedHash is read with the algorithm stored in the
+// event; a prefix naming another algorithm is rejected.
+func (s *SmartContract) VerifyOffChainData(ctx contractapi.TransactionContextInterface, assetID string, txID string, providedHash string) (*OffChainVerification, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	event, err := committedEvent(ctx, asset, txID)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, expected, err := resolveOffChainHash(event.HashAlgorithm, strings.ToLower(event.OffChainDataHash))
+	if err != nil {
+		// Events recorded before hashes were validated are compared as stored.
+		algorithm, expected = defaultHashAlgorithm, strings.TrimPrefix(strings.ToLower(event.OffChainDataHash), defaultHashAlgorithm+":")
+	}
+	_, provided, err := resolveOffChainHash(algorithm, strings.ToLower(strings.TrimSpace(providedHash)))
+	if err != nil {
+		return nil, err
+	}
+	verification := &OffChainVerification{
+		Match:         provided == expected,
+		HashAlgorithm: algorithm,
+		ExpectedHash:  event.OffChainDataHash,
+		CurrentHash:   event.OffChainDataHash,
+	}
+	superseded := supersessions(assetEvents(ctx, asset))
+	for current, ok := superseded[txID]; ok; current, ok = superseded[current.TxID] {
+		verification.Superseded = true
+		verification.SupersededByTxID = current.TxID
+		verification.CurrentHash = current.OffChainDataHash
+	}
+	return verification, nil
+}
+
+// SupersedeOffChainData records a HASH_SUPERSEDED event committing newHash in
+// place of the hash committed by previousTxID, for instance after a document
+// was legitimately re-uploaded. The earlier event is left untouched. Only the
+// MSP that recorded the earlier event or the admin MSP may supersede it. It
+// returns the txID that recorded the supersession, or the one that already
+// recorded clientEventID for the asset.
+func (s *SmartContract) SupersedeOffChainData(ctx contractapi.TransactionContextInterface, assetID string, previousTxID string, newHash string, reason string, clientEventID string) (string, error) {
+	if newHash == "" {
+		return "", fmt.Errorf("%w: newHash must not be empty", ErrInvalidArgument)
+	}
+	if reason == "" {
+		return "", fmt.Errorf("%w: a reason is required", ErrInvalidArgument)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if txID, err := priorClientEvent(ctx, assetID, clientEventID); err != nil || txID != "" {
+		return txID, err
+	}
+	previous, err := committedEvent(ctx, asset, previousTxID)
+	if err != nil {
+		return "", err
+	}
+	if next, ok := supersessions(assetEvents(ctx, asset))[previousTxID]; ok {
+		return "", fmt.Errorf("%w: the hash of %s was already superseded by %s", ErrInvalidState, previousTxID, next.TxID)
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %w", err)
+	}
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return "", err
+	}
+	if clientMSPID != previous.AgentID && !admin {
+		return "", fmt.Errorf("%w: client from %s may not supersede a hash recorded by %s", ErrUnauthorized, clientMSPID, previous.AgentID)
+	}
+	payload, err := json.Marshal(HashSupersessionPayload{
+		PreviousTxID: previousTxID,
+		PreviousHash: previous.OffChainDataHash,
+		Reason:       reason,
+	})
+	if err != nil {
+		return "", err
+	}
+	event := ProvenanceEvent{
+		EventType:          EventHashSuperseded,
+		AgentID:            clientMSPID,
+		ClientEventID:      clientEventID,
+		OffChainDataHash:   newHash,
+		OnChainDataPayload: string(payload),
+	}
+	return s.recordClientEvent(ctx, asset, event, "")
+}